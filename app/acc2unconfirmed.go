@@ -81,3 +81,8 @@ func (acc2unc *Account2UnconfirmedTx) CommitRemove(timestamp int64) {
 func (acc2unc *Account2UnconfirmedTx) ClearRemoveList() {
 	acc2unc.removeList = acc2unc.removeList[:0]
 }
+
+// Size returns the number of accounts which have an unconfirmed tx
+func (acc2unc *Account2UnconfirmedTx) Size() int {
+	return len(acc2unc.auMap)
+}
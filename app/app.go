@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/cosmos/cosmos-sdk/server"
 	"github.com/spf13/viper"
@@ -19,6 +20,7 @@ import (
 
 	bam "github.com/cosmos/cosmos-sdk/baseapp"
 	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/store"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/module"
 	"github.com/cosmos/cosmos-sdk/version"
@@ -130,6 +132,7 @@ func MakeCodec() *codec.Codec {
 // Extended ABCI application
 type CetChainApp struct {
 	*bam.BaseApp
+	cms       store.CommitMultiStore
 	cdc       *codec.Codec
 	txDecoder sdk.TxDecoder // unmarshal []byte into sdk.Tx
 	txCount   int64
@@ -177,15 +180,22 @@ type CetChainApp struct {
 	marketKeeper    market.Keeper
 	bancorKeeper    bancorlite.Keeper
 	msgQueProducer  msgqueue.MsgSender
+	pubRouter       *pubMsgRouter
 	aliasKeeper     alias.Keeper
 	commentKeeper   comment.Keeper
-	ts              *tserver.TradeServer
+	ts              *tserver.TradeServer // started by StartTradeServer
 	once            *sync.Once
+	abciMtx         sync.Mutex // shared by the node's local ABCI client and the embedded LCD
 
 	enableUnconfirmedLimit bool
 	currBlockTime          int64
 	account2UnconfirmedTx  *Account2UnconfirmedTx
 
+	metrics       *Metrics
+	blockStart    time.Time
+	blockProfiles []moduleProfile
+	profiling     bool
+
 	// the module manager
 	mm *module.Manager
 
@@ -199,13 +209,19 @@ func NewCetChainApp(logger log.Logger, db dbm.DB, traceStore io.Writer, loadLate
 
 	cdc := MakeCodec()
 
-	txDecoder := auth.DefaultTxDecoder(cdc)
-	bApp := bam.NewBaseApp(appName, logger, db, txDecoder, baseAppOptions...)
+	// shared by BaseApp and CetChainApp, so each tx is decoded once in CheckTx and DeliverTx
+	txDecoder := newTxDecodeCache(newTxDecoderFromConfig(cdc), txDecodeCacheSize).Decode
+	// kept by the app to read the commit hashes of the stores, the options of
+	// the BaseApp, such as the pruning, are applied after it is set
+	cms := store.NewCommitMultiStore(db)
+	bApp := bam.NewBaseApp(appName, logger, db, txDecoder,
+		append([]func(*bam.BaseApp){func(bApp *bam.BaseApp) { bApp.SetCMS(cms) }}, baseAppOptions...)...)
 	bApp.SetCommitMultiStoreTracer(traceStore)
 	bApp.SetAppVersion(version.Version)
 	bam.SetHaltHeight(viper.GetUint64(server.FlagHaltHeight))(bApp)
 
 	app := newCetChainApp(bApp, cdc, invCheckPeriod, txDecoder)
+	app.cms = cms
 	app.initPubMsgBuf()
 	app.initMsgQue()
 	app.initKeepers(invCheckPeriod)
@@ -255,6 +271,7 @@ func newCetChainApp(bApp *bam.BaseApp, cdc *codec.Codec, invCheckPeriod uint, tx
 		txDecoder:      txDecoder,
 		cdc:            cdc,
 		invCheckPeriod: invCheckPeriod,
+		metrics:        NopMetrics(),
 		keyMain:        sdk.NewKVStoreKey(bam.MainStoreKey),
 		keyAccount:     sdk.NewKVStoreKey(auth.StoreKey),
 		keyAccountX:    sdk.NewKVStoreKey(authx.StoreKey),
@@ -277,19 +294,23 @@ func newCetChainApp(bApp *bam.BaseApp, cdc *codec.Codec, invCheckPeriod uint, tx
 }
 
 func (app *CetChainApp) initMsgQue() {
-	app.msgQueProducer = msgqueue.NewProducer(app.Logger()) // TODO
-	if isOpenTs() {
-		conf, err := initConf()
+	if filePath := getPubSubscriptionFile(); len(filePath) != 0 {
+		conf, err := LoadPubSubscriptionConfig(filePath)
 		if err != nil {
-			panic(fmt.Sprintf("init trade-server conf faild, err : %s\b", err.Error()))
-		}
-		if app.ts = tserver.NewTradeServer(conf, CreateContextAndRegisterRoutes); app.ts == nil {
-			panic("Init trade-server failed")
+			panic(fmt.Sprintf("load pub subscription conf failed, err : %s", err.Error()))
 		}
-		app.ts.Start(conf)
+		app.pubRouter = newPubMsgRouter(conf, app.Logger())
+		app.msgQueProducer = app.pubRouter
+	} else {
+		app.msgQueProducer = msgqueue.NewProducer(app.Logger()) // TODO
 	}
 }
 
+// CloseMsgQue closes all the writers of the msg queue producer
+func (app *CetChainApp) CloseMsgQue() {
+	app.msgQueProducer.Close()
+}
+
 func (app *CetChainApp) initKeepers(invCheckPeriod uint) {
 	app.paramsKeeper = params.NewKeeper(app.cdc, app.keyParams, app.tkeyParams, params.DefaultCodespace)
 	// define the accountKeeper
@@ -553,24 +574,34 @@ func (app *CetChainApp) registerRoutesWithOrder(modules []module.AppModule) {
 }
 
 // initialize BaseApp
-func (app *CetChainApp) mountStores() {
-	app.MountStores(app.keyMain, app.keyAccount, app.keySupply, app.keyStaking, app.keyDistr,
+// kvStoreKeys returns the KV stores mounted by mountStores
+func (app *CetChainApp) kvStoreKeys() []*sdk.KVStoreKey {
+	return []*sdk.KVStoreKey{app.keyMain, app.keyAccount, app.keySupply, app.keyStaking, app.keyDistr,
 		app.keySlashing, app.keyGov, app.keyParams,
-		app.tkeyParams, app.tkeyStaking,
 		app.keyAccountX, app.keyAsset, app.keyMarket, app.keyIncentive,
 		app.keyBancor, app.keyAlias, app.keyComment, app.keyStakingX,
-	)
+	}
+}
+
+func (app *CetChainApp) mountStores() {
+	var keys []sdk.StoreKey
+	for _, key := range app.kvStoreKeys() {
+		keys = append(keys, key)
+	}
+	app.MountStores(append(keys, app.tkeyParams, app.tkeyStaking)...)
 }
 
 // application updates every begin block
 func (app *CetChainApp) beginBlocker(ctx sdk.Context, req abci.RequestBeginBlock) abci.ResponseBeginBlock {
+	defer app.observeDuration(app.metrics.BeginBlockerSeconds, time.Now())
+	app.resetBlockProfile()
 	app.height = ctx.BlockHeight()
 	app.resetPubMsgBuf()
 	if app.msgQueProducer.IsOpenToggle() {
 		app.txCount = req.Header.TotalTxs - req.Header.NumTxs
 		app.pushNewHeightInfo(ctx)
 	}
-	ret := app.mm.BeginBlock(ctx, req)
+	ret := app.moduleBeginBlock(ctx, req)
 	if app.msgQueProducer.IsOpenToggle() {
 		ret.Events = collectKafkaEvents(ret.Events, app)
 		app.notifyBeginBlock(ret.Events)
@@ -585,7 +616,8 @@ func (app *CetChainApp) beginBlocker(ctx sdk.Context, req abci.RequestBeginBlock
 // application updates every end block
 // nolint: unparam
 func (app *CetChainApp) endBlocker(ctx sdk.Context, req abci.RequestEndBlock) abci.ResponseEndBlock {
-	ret := app.mm.EndBlock(ctx, req)
+	defer app.observeDuration(app.metrics.EndBlockerSeconds, time.Now())
+	ret := app.moduleEndBlock(ctx, req)
 	if app.msgQueProducer.IsOpenToggle() {
 		ret.Events = collectKafkaEvents(ret.Events, app)
 		app.notifyEndBlock(ret.Events)
@@ -630,24 +662,37 @@ func (app *CetChainApp) appendPubMsg(msg PubMsg) {
 }
 func (app *CetChainApp) appendPubEvent(event abci.Event) {
 	for _, attr := range event.Attributes {
-		app.appendPubMsg(PubMsg{Key: attr.Key, Value: attr.Value})
+		if app.isPubKeyWanted(string(attr.Key)) {
+			app.appendPubMsg(PubMsg{Key: attr.Key, Value: attr.Value})
+		}
 	}
 }
 func (app *CetChainApp) appendPubMsgKV(key string, val []byte) {
 	app.pubMsgs = append(app.pubMsgs, PubMsg{Key: []byte(key), Value: val})
 }
 
+// isPubKeyWanted returns false if no sink publishes the messages with this key,
+// so there is no need to build them
+func (app *CetChainApp) isPubKeyWanted(key string) bool {
+	return app.pubRouter == nil || app.pubRouter.wantsKey(key)
+}
+
 /* "override" ABCI methods */
 
 func (app *CetChainApp) CheckTx(req abci.RequestCheckTx) abci.ResponseCheckTx {
 	if p := app.GetPlugin(); p != nil {
 		if err := p.PreCheckTx(req, app.txDecoder, app.Logger()); err != nil {
+			app.metrics.CheckTxRejections.With("cause", RejectCausePlugin).Add(1)
 			return dex.ResponseFrom(err)
 		}
 	}
 
 	if !app.enableUnconfirmedLimit {
-		return app.BaseApp.CheckTx(req)
+		ret := app.BaseApp.CheckTx(req)
+		if !ret.IsOK() {
+			app.metrics.CheckTxRejections.With("cause", RejectCauseAnte).Add(1)
+		}
+		return ret
 	}
 
 	var result sdk.Result
@@ -661,6 +706,7 @@ func (app *CetChainApp) CheckTx(req abci.RequestCheckTx) abci.ResponseCheckTx {
 	}
 
 	if err != nil || !ok {
+		app.metrics.CheckTxRejections.With("cause", RejectCauseDecode).Add(1)
 		return abci.ResponseCheckTx{
 			Code:   uint32(result.Code),
 			Data:   result.Data,
@@ -681,6 +727,7 @@ func (app *CetChainApp) CheckTx(req abci.RequestCheckTx) abci.ResponseCheckTx {
 	}
 
 	if otherTxExist {
+		app.metrics.CheckTxRejections.With("cause", RejectCauseUnconfirmedLimit).Add(1)
 		return dex.ResponseFrom(errTooManyUnconfirmedTx)
 	}
 	ret := app.BaseApp.CheckTx(req)
@@ -688,6 +735,8 @@ func (app *CetChainApp) CheckTx(req abci.RequestCheckTx) abci.ResponseCheckTx {
 		for _, signer := range signers {
 			app.account2UnconfirmedTx.Add(signer, hashid, app.currBlockTime)
 		}
+	} else {
+		app.metrics.CheckTxRejections.With("cause", RejectCauseAnte).Add(1)
 	}
 	return ret
 }
@@ -705,6 +754,7 @@ func (app *CetChainApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDelive
 	}
 
 	ret := app.BaseApp.DeliverTx(req)
+	app.countDeliveredMsgs(stdTx, formatOK, ret.Code)
 
 	if app.msgQueProducer.IsOpenToggle() {
 		if formatOK {
@@ -725,14 +775,25 @@ func (app *CetChainApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDelive
 }
 
 func (app *CetChainApp) Commit() abci.ResponseCommit {
+	defer app.observeDuration(app.metrics.CommitSeconds, time.Now())
 	if app.msgQueProducer.IsOpenToggle() {
+		app.metrics.PubMsgsPerBlock.Observe(float64(len(app.pubMsgs)))
 		for _, msg := range app.pubMsgs {
-			app.msgQueProducer.SendMsg(msg.Key, msg.Value)
+			app.metrics.PubMsgs.With("key", string(msg.Key)).Add(1)
+			if app.pubRouter != nil {
+				app.pubRouter.sendPubMsg(msg)
+			} else {
+				app.msgQueProducer.SendMsg(msg.Key, msg.Value)
+			}
 		}
-		app.msgQueProducer.SendMsg([]byte("commit"), []byte("{}"))
+		app.msgQueProducer.SendMsg([]byte(pubMsgKeyCommit), []byte("{}"))
 	}
 	if app.enableUnconfirmedLimit {
 		app.account2UnconfirmedTx.CommitRemove(app.currBlockTime)
+		app.metrics.UnconfirmedLimiterSize.Set(float64(app.account2UnconfirmedTx.Size()))
 	}
-	return app.BaseApp.Commit()
+	ret := app.BaseApp.Commit()
+	app.logSlowBlock()
+	app.logStoreHashes()
+	return ret
 }
@@ -17,6 +17,8 @@ import (
 	stypes "github.com/cosmos/cosmos-sdk/x/staking/types"
 
 	dex "github.com/coinexchain/cet-sdk/types"
+
+	dexcodec "github.com/coinexchain/dex/codec"
 )
 
 type TxExtraInfo struct {
@@ -38,6 +40,9 @@ type NewHeightInfo struct {
 }
 
 func (app *CetChainApp) pushNewHeightInfo(ctx sdk.Context) {
+	if !app.isPubKeyWanted("height_info") {
+		return
+	}
 	msg := NewHeightInfo{
 		ChainID:       ctx.BlockHeader().ChainID,
 		Height:        ctx.BlockHeight(),
@@ -91,6 +96,17 @@ func getType(myvar interface{}) string {
 }
 
 func (app *CetChainApp) notifyTx(req abci.RequestDeliverTx, stdTx auth.StdTx, ret abci.ResponseDeliverTx) {
+	defer func() {
+		app.txCount++
+	}()
+
+	wantTx := app.isPubKeyWanted("notify_tx")
+	wantUnbonding := app.isPubKeyWanted("begin_unbonding")
+	wantRedelegation := app.isPubKeyWanted("begin_redelegation")
+	if !wantTx && !wantUnbonding && !wantRedelegation {
+		return
+	}
+
 	events := ret.Events
 	transfers := make([]TransferRecord, 0, 10)
 	ok := ret.Code == uint32(sdk.CodeOK)
@@ -99,14 +115,18 @@ func (app *CetChainApp) notifyTx(req abci.RequestDeliverTx, stdTx auth.StdTx, re
 	for i := 0; ok && i < len(events); i++ {
 		if events[i].Type == stypes.EventTypeUnbond {
 			if i+1 <= len(events) {
-				val := getNotificationBeginUnbonding(events[i : i+2])
-				unbondingMsgList = append(unbondingMsgList, val)
+				if wantUnbonding {
+					val := getNotificationBeginUnbonding(events[i : i+2])
+					unbondingMsgList = append(unbondingMsgList, val)
+				}
 				i++
 			}
 		} else if events[i].Type == stypes.EventTypeRedelegate {
 			if i+1 <= len(events) {
-				val := getNotificationBeginRedelegation(events[i : i+2])
-				redelegationMsgList = append(redelegationMsgList, val)
+				if wantRedelegation {
+					val := getNotificationBeginRedelegation(events[i : i+2])
+					redelegationMsgList = append(redelegationMsgList, val)
+				}
 				i++
 			}
 		} else if events[i].Type == "transfer" && i+2 <= len(events) {
@@ -116,16 +136,27 @@ func (app *CetChainApp) notifyTx(req abci.RequestDeliverTx, stdTx auth.StdTx, re
 		}
 	}
 
-	defer func() {
-		app.txCount++
-	}()
+	if wantTx {
+		app.appendNotificationTx(req, stdTx, ret, transfers)
+	}
+	for _, val := range unbondingMsgList {
+		app.appendPubMsgKV("begin_unbonding", val)
+	}
+	for _, val := range redelegationMsgList {
+		app.appendPubMsgKV("begin_redelegation", val)
+	}
+}
+
+func (app *CetChainApp) appendNotificationTx(req abci.RequestDeliverTx, stdTx auth.StdTx,
+	ret abci.ResponseDeliverTx, transfers []TransferRecord) {
 
 	msgTypes := make([]string, len(stdTx.Msgs))
 	for i, msg := range stdTx.Msgs {
 		msgTypes[i] = getType(msg)
 	}
 
-	bytes, errJSON := json.Marshal(&stdTx)
+	// the trade-server reads the msgs of the encoding/json output, not amino JSON
+	bytes, errJSON := dexcodec.MarshalGoJSONStdTx(stdTx)
 	if errJSON != nil {
 		return
 	}
@@ -162,13 +193,24 @@ func (app *CetChainApp) notifyTx(req abci.RequestDeliverTx, stdTx auth.StdTx, re
 		return
 	}
 
-	app.appendPubMsgKV("notify_tx", bytes)
-	for _, val := range unbondingMsgList {
-		app.appendPubMsgKV("begin_unbonding", val)
+	app.appendPubMsg(PubMsg{
+		Key:       []byte("notify_tx"),
+		Value:     bytes,
+		addresses: getNotificationTxAddresses(n4s),
+		msgTypes:  msgTypes,
+	})
+}
+
+// the addresses involved in a tx, used to filter notify_tx by subscription
+func getNotificationTxAddresses(n4s *NotificationTx) []string {
+	addresses := make([]string, 0, len(n4s.Signers)+2*len(n4s.Transfers))
+	for _, signer := range n4s.Signers {
+		addresses = append(addresses, signer.String())
 	}
-	for _, val := range redelegationMsgList {
-		app.appendPubMsgKV("begin_redelegation", val)
+	for _, transfer := range n4s.Transfers {
+		addresses = append(addresses, transfer.Sender, transfer.Recipient)
 	}
+	return addresses
 }
 
 type NotificationBeginRedelegation struct {
@@ -298,13 +340,13 @@ func (app *CetChainApp) notifyBeginBlock(events []abci.Event) {
 		//for _, attr := range event.Attributes {
 		//	fmt.Printf("= K: %s; V: %s\n", attr.Key, attr.Value)
 		//}
-		if event.Type == sltypes.EventTypeSlash {
+		if event.Type == sltypes.EventTypeSlash && app.isPubKeyWanted("slash") {
 			val := getNotificationSlash(event)
 			app.appendPubMsgKV("slash", val)
-		} else if subscribedDistr && event.Type == distrtypes.EventTypeCommission {
+		} else if subscribedDistr && event.Type == distrtypes.EventTypeCommission && app.isPubKeyWanted("validator_commission") {
 			val := getValidatorCommissionMsg(event)
 			app.appendPubMsgKV("validator_commission", val)
-		} else if subscribedDistr && event.Type == distrtypes.EventTypeRewards {
+		} else if subscribedDistr && event.Type == distrtypes.EventTypeRewards && app.isPubKeyWanted("delegator_rewards") {
 			val := getDelegatorRewardsMsg(event)
 			app.appendPubMsgKV("delegator_rewards", val)
 		}
@@ -318,10 +360,10 @@ func (app *CetChainApp) notifyEndBlock(events []abci.Event) {
 		//for _, attr := range event.Attributes {
 		//	fmt.Printf("= K: %s; V: %s\n", attr.Key, attr.Value)
 		//}
-		if event.Type == stypes.EventTypeCompleteUnbonding {
+		if event.Type == stypes.EventTypeCompleteUnbonding && app.isPubKeyWanted("complete_unbonding") {
 			val := getNotificationCompleteUnbonding(event)
 			app.appendPubMsgKV("complete_unbonding", val)
-		} else if event.Type == stypes.EventTypeCompleteRedelegation {
+		} else if event.Type == stypes.EventTypeCompleteRedelegation && app.isPubKeyWanted("complete_redelegation") {
 			val := getNotificationCompleteRedelegation(event)
 			app.appendPubMsgKV("complete_redelegation", val)
 		}
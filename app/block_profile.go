@@ -0,0 +1,213 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// FlagSlowBlockThreshold is the duration above which a block is logged with
+// the profiles of its BeginBlockers and EndBlockers, zero disables the log
+const FlagSlowBlockThreshold = "slow-block-threshold"
+
+const (
+	phaseBeginBlock = "begin_block"
+	phaseEndBlock   = "end_block"
+)
+
+// moduleProfile is the cost of one module's BeginBlock or EndBlock
+type moduleProfile struct {
+	phase    string
+	module   string
+	duration time.Duration
+	reads    uint64
+	writes   uint64
+}
+
+func (p moduleProfile) String() string {
+	return fmt.Sprintf("%s/%s=%s,r:%d,w:%d",
+		p.module, p.phase, p.duration, p.reads, p.writes)
+}
+
+type storeCounter struct {
+	reads  uint64
+	writes uint64
+}
+
+var _ sdk.KVStore = countingKVStore{}
+
+// countingKVStore counts the operations on a KVStore, every item returned by
+// an iterator is counted as a read
+type countingKVStore struct {
+	sdk.KVStore
+	counter *storeCounter
+}
+
+func (s countingKVStore) Get(key []byte) []byte {
+	s.counter.reads++
+	return s.KVStore.Get(key)
+}
+
+func (s countingKVStore) Has(key []byte) bool {
+	s.counter.reads++
+	return s.KVStore.Has(key)
+}
+
+func (s countingKVStore) Set(key, value []byte) {
+	s.counter.writes++
+	s.KVStore.Set(key, value)
+}
+
+func (s countingKVStore) Delete(key []byte) {
+	s.counter.writes++
+	s.KVStore.Delete(key)
+}
+
+func (s countingKVStore) Iterator(start, end []byte) sdk.Iterator {
+	return countingIterator{Iterator: s.KVStore.Iterator(start, end), counter: s.counter}
+}
+
+func (s countingKVStore) ReverseIterator(start, end []byte) sdk.Iterator {
+	return countingIterator{Iterator: s.KVStore.ReverseIterator(start, end), counter: s.counter}
+}
+
+type countingIterator struct {
+	sdk.Iterator
+	counter *storeCounter
+}
+
+func (it countingIterator) Value() []byte {
+	it.counter.reads++
+	return it.Iterator.Value()
+}
+
+var _ sdk.MultiStore = countingMultiStore{}
+
+type countingMultiStore struct {
+	sdk.MultiStore
+	counter *storeCounter
+}
+
+func (ms countingMultiStore) GetKVStore(key sdk.StoreKey) sdk.KVStore {
+	return countingKVStore{KVStore: ms.MultiStore.GetKVStore(key), counter: ms.counter}
+}
+
+// the writes through ctx.CacheContext() are counted when they are made, the
+// Write of the cache goes to the stores below the counting ones
+func (ms countingMultiStore) CacheMultiStore() sdk.CacheMultiStore {
+	cms := ms.MultiStore.CacheMultiStore()
+	return countingCacheMultiStore{
+		countingMultiStore: countingMultiStore{MultiStore: cms, counter: ms.counter},
+		write:              cms.Write,
+	}
+}
+
+var _ sdk.CacheMultiStore = countingCacheMultiStore{}
+
+type countingCacheMultiStore struct {
+	countingMultiStore
+	write func()
+}
+
+func (ms countingCacheMultiStore) Write() {
+	ms.write()
+}
+
+// profileModule runs f with a context whose store operations are counted, the
+// result is recorded in the metrics and the profiles of the block. f runs
+// directly when neither the metrics nor the slow block log are enabled.
+func (app *CetChainApp) profileModule(ctx sdk.Context, phase, module string, f func(ctx sdk.Context)) {
+	if !app.profiling {
+		f(ctx)
+		return
+	}
+	counter := &storeCounter{}
+	ctx = ctx.WithMultiStore(countingMultiStore{MultiStore: ctx.MultiStore(), counter: counter})
+
+	start := time.Now()
+	f(ctx)
+	profile := moduleProfile{
+		phase:    phase,
+		module:   module,
+		duration: time.Since(start),
+		reads:    counter.reads,
+		writes:   counter.writes,
+	}
+
+	app.metrics.ModuleBlockerSeconds.With("phase", phase, "module", module).Observe(profile.duration.Seconds())
+	app.metrics.ModuleStoreReads.With("phase", phase, "module", module).Add(float64(profile.reads))
+	app.metrics.ModuleStoreWrites.With("phase", phase, "module", module).Add(float64(profile.writes))
+	app.blockProfiles = append(app.blockProfiles, profile)
+}
+
+// see module.Manager#BeginBlock()
+func (app *CetChainApp) moduleBeginBlock(ctx sdk.Context, req abci.RequestBeginBlock) abci.ResponseBeginBlock {
+	ctx = ctx.WithEventManager(sdk.NewEventManager())
+
+	for _, moduleName := range app.mm.OrderBeginBlockers {
+		app.profileModule(ctx, phaseBeginBlock, moduleName, func(ctx sdk.Context) {
+			app.mm.Modules[moduleName].BeginBlock(ctx, req)
+		})
+	}
+
+	return abci.ResponseBeginBlock{
+		Events: ctx.EventManager().ABCIEvents(),
+	}
+}
+
+// see module.Manager#EndBlock()
+func (app *CetChainApp) moduleEndBlock(ctx sdk.Context, req abci.RequestEndBlock) abci.ResponseEndBlock {
+	ctx = ctx.WithEventManager(sdk.NewEventManager())
+	validatorUpdates := []abci.ValidatorUpdate{}
+
+	for _, moduleName := range app.mm.OrderEndBlockers {
+		var moduleValUpdates []abci.ValidatorUpdate
+		app.profileModule(ctx, phaseEndBlock, moduleName, func(ctx sdk.Context) {
+			moduleValUpdates = app.mm.Modules[moduleName].EndBlock(ctx, req)
+		})
+
+		// use these validator updates if provided, the module manager assumes
+		// only one module will update the validator set
+		if len(moduleValUpdates) > 0 {
+			if len(validatorUpdates) > 0 {
+				panic("validator EndBlock updates already set by a previous module")
+			}
+
+			validatorUpdates = moduleValUpdates
+		}
+	}
+
+	return abci.ResponseEndBlock{
+		ValidatorUpdates: validatorUpdates,
+		Events:           ctx.EventManager().ABCIEvents(),
+	}
+}
+
+func (app *CetChainApp) resetBlockProfile() {
+	app.blockStart = time.Now()
+	app.blockProfiles = app.blockProfiles[:0]
+	app.profiling = !app.metrics.nop || viper.GetDuration(FlagSlowBlockThreshold) > 0
+}
+
+// logSlowBlock must be called after the block is committed
+func (app *CetChainApp) logSlowBlock() {
+	threshold := viper.GetDuration(FlagSlowBlockThreshold)
+	if threshold <= 0 || app.blockStart.IsZero() {
+		return
+	}
+	elapsed := time.Since(app.blockStart)
+	if elapsed < threshold {
+		return
+	}
+	profiles := make([]string, len(app.blockProfiles))
+	for i, p := range app.blockProfiles {
+		profiles[i] = p.String()
+	}
+	app.Logger().Info("slow block", "height", app.height, "duration", elapsed,
+		"modules", strings.Join(profiles, " "))
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/store/dbadapter"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/testutil"
+)
+
+func TestCountingKVStore(t *testing.T) {
+	counter := &storeCounter{}
+	store := countingKVStore{KVStore: dbadapter.Store{DB: dbm.NewMemDB()}, counter: counter}
+
+	store.Set([]byte("a"), []byte("1"))
+	store.Set([]byte("b"), []byte("2"))
+	store.Delete([]byte("b"))
+	require.Equal(t, []byte("1"), store.Get([]byte("a")))
+	require.False(t, store.Has([]byte("b")))
+	require.Equal(t, uint64(3), counter.writes)
+	require.Equal(t, uint64(2), counter.reads)
+
+	store.Set([]byte("c"), []byte("3"))
+	it := store.Iterator(nil, nil)
+	for ; it.Valid(); it.Next() {
+		_ = it.Value()
+	}
+	it.Close()
+	require.Equal(t, uint64(4), counter.reads)
+}
+
+func TestCountingCacheContext(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	app := initAppWithBaseAccounts(auth.BaseAccount{Address: addr})
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, Time: time.Now()}})
+
+	counter := &storeCounter{}
+	ctx := app.NewContext(false, abci.Header{Height: 1})
+	ctx = ctx.WithMultiStore(countingMultiStore{MultiStore: ctx.MultiStore(), counter: counter})
+	cacheCtx, write := ctx.CacheContext()
+	cacheCtx.KVStore(app.keyAccountX).Set([]byte("a"), []byte("1"))
+	nestedCtx, _ := cacheCtx.CacheContext()
+	nestedCtx.KVStore(app.keyAccountX).Set([]byte("b"), []byte("2"))
+	write()
+	require.Equal(t, uint64(2), counter.writes)
+	require.Equal(t, []byte("1"), ctx.KVStore(app.keyAccountX).Get([]byte("a")))
+	require.Equal(t, uint64(1), counter.reads)
+}
+
+func TestBlockProfileDisabled(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	app := initAppWithBaseAccounts(auth.BaseAccount{Address: addr})
+
+	// no metrics and no slow block log
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1, Time: time.Now()}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	require.Empty(t, app.blockProfiles)
+}
+
+func TestBlockProfile(t *testing.T) {
+	viper.Set(FlagSlowBlockThreshold, time.Hour)
+	defer viper.Set(FlagSlowBlockThreshold, 0)
+	_, _, addr := testutil.KeyPubAddr()
+	app := initAppWithBaseAccounts(auth.BaseAccount{Address: addr})
+
+	header := abci.Header{Height: 1, Time: time.Now()}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+
+	expected := make([]string, 0)
+	for _, module := range app.mm.OrderBeginBlockers {
+		expected = append(expected, phaseBeginBlock+":"+module)
+	}
+	for _, module := range app.mm.OrderEndBlockers {
+		expected = append(expected, phaseEndBlock+":"+module)
+	}
+	actual := make([]string, 0, len(app.blockProfiles))
+	for _, p := range app.blockProfiles {
+		actual = append(actual, p.phase+":"+p.module)
+	}
+	require.Equal(t, expected, actual)
+
+	// the staking EndBlocker reads the validator queues
+	for _, p := range app.blockProfiles {
+		if p.phase == phaseEndBlock && p.module == "staking" {
+			require.True(t, p.reads > 0)
+		}
+	}
+
+	app.Commit()
+
+	// the profiles are reset in the next block
+	header = abci.Header{Height: 2, Time: time.Now()}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	require.Equal(t, len(app.mm.OrderBeginBlockers), len(app.blockProfiles))
+}
+
+func TestModuleProfileString(t *testing.T) {
+	p := moduleProfile{
+		phase:    phaseEndBlock,
+		module:   "market",
+		duration: 3 * time.Millisecond,
+		reads:    10,
+		writes:   2,
+	}
+	require.Equal(t, "market/end_block=3ms,r:10,w:2", p.String())
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	dexcodec "github.com/coinexchain/dex/codec"
+)
+
+// the interfaces in codec.GetSupportList(), they are covered by their implementations
+var codonInterfaces = map[string]bool{
+	"github.com/cosmos/cosmos-sdk/types.Msg":               true,
+	"github.com/cosmos/cosmos-sdk/x/auth/exported.Account": true,
+	"github.com/cosmos/cosmos-sdk/x/gov/types.Content":     true,
+	"github.com/tendermint/tendermint/crypto.PubKey":       true,
+}
+
+func typeNameOf(v interface{}) string {
+	t := reflect.TypeOf(v)
+	return t.PkgPath() + "." + t.Name()
+}
+
+// TestCodonAminoDifferential checks that a value decoded by codon is the same
+// as the one decoded by amino, by comparing their amino encodings
+func TestCodonAminoDifferential(t *testing.T) {
+	cdc := MakeCodec()
+	r := dexcodec.NewRandSrc(0)
+	compared := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		v := dexcodec.RandAny(r)
+		name := typeNameOf(v)
+
+		aminoBytes, err := cdc.MarshalBinaryBare(v)
+		if err != nil {
+			// out of the range of amino, e.g. a time before year 1
+			continue
+		}
+		aminoDecoded := reflect.New(reflect.TypeOf(v))
+		require.Nil(t, cdc.UnmarshalBinaryBare(aminoBytes, aminoDecoded.Interface()), name)
+
+		var buf bytes.Buffer
+		require.Nil(t, dexcodec.EncodeAny(&buf, v), name)
+		codonDecoded, n, err := dexcodec.DecodeAny(buf.Bytes())
+		require.Nil(t, err, name)
+		require.Equal(t, buf.Len(), n, name)
+
+		require.Equal(t, cdc.MustMarshalBinaryBare(aminoDecoded.Elem().Interface()),
+			cdc.MustMarshalBinaryBare(codonDecoded), name)
+		compared[name]++
+	}
+
+	for _, name := range dexcodec.GetSupportList() {
+		if !codonInterfaces[name] {
+			require.True(t, compared[name] > 0, name)
+		}
+	}
+}
+
+// a new msg or account registered to amino must be aliased in codec/types.go,
+// and codec/codec.go must be regenerated by codec/run
+func TestCodonCoversRegisteredTypes(t *testing.T) {
+	registered, err := dexcodec.GetRegisteredTypes(MakeCodec())
+	require.Nil(t, err)
+	require.NotEmpty(t, registered)
+
+	supported := make(map[string]bool)
+	for _, name := range dexcodec.GetSupportList() {
+		supported[name] = true
+	}
+	for _, rt := range registered {
+		require.True(t, supported[typeNameOf(rt.Value)], "no codon encoder for "+rt.Name)
+	}
+}
@@ -33,10 +33,7 @@ func (app *CetChainApp) ExportAppStateAndValidators(forZeroHeight bool, jailWhit
 
 	genState := app.mm.ExportGenesis(ctx)
 	if forZeroHeight {
-		var ig incentive.GenesisState
-		incentive.ModuleCdc.MustUnmarshalJSON(genState[incentive.ModuleName], &ig)
-		ig.State.HeightAdjustment = ig.State.HeightAdjustment + ctx.BlockHeader().Height
-		genState[incentive.ModuleName] = incentive.ModuleCdc.MustMarshalJSON(ig)
+		genState[incentive.ModuleName] = adjustIncentiveHeight(ctx, genState[incentive.ModuleName])
 	}
 
 	appState, err = codec.MarshalJSONIndent(app.cdc, genState)
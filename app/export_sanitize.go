@@ -0,0 +1,283 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/cet-sdk/modules/incentive"
+)
+
+// AddressMapping is an address of the exported state and its replacement
+type AddressMapping struct {
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+}
+
+// ValidatorMapping is a validator of the exported state and the test
+// validator replacing it
+type ValidatorMapping struct {
+	OriginalOperator      string `json:"original_operator"`
+	ReplacementOperator   string `json:"replacement_operator"`
+	OriginalConsPubKey    string `json:"original_consensus_pubkey"`
+	ReplacementConsPubKey string `json:"replacement_consensus_pubkey"`
+}
+
+// SanitizeMapping records the replacements of a sanitized export
+type SanitizeMapping struct {
+	Accounts   []AddressMapping   `json:"accounts"`
+	Validators []ValidatorMapping `json:"validators"`
+}
+
+// ExportSanitizedAppStateAndValidators exports the state for a test network
+// as a zero height genesis. Every address, except the ones of the module
+// accounts and the incentive pool, is replaced by the address of a test key derived from the seed
+// and the address, while the balances, orders, tokens and markets are kept.
+// The bonded validators with the most power are taken over by the test
+// validators, one for each, and the others are jailed.
+func (app *CetChainApp) ExportSanitizedAppStateAndValidators(seed string, testValidators []crypto.PubKey) (
+	appState json.RawMessage, validators []tmtypes.GenesisValidator, mapping SanitizeMapping, err error) {
+
+	ctx := app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+
+	bonded := app.stakingKeeper.GetBondedValidatorsByPower(ctx)
+	used := make(map[string]bool, len(testValidators))
+	for _, pk := range testValidators {
+		if used[string(pk.Address())] {
+			return nil, nil, mapping, fmt.Errorf("duplicate test validator %s", sdk.MustBech32ifyConsPub(pk))
+		}
+		used[string(pk.Address())] = true
+	}
+	if len(testValidators) == 0 || len(testValidators) > len(bonded) {
+		return nil, nil, mapping, fmt.Errorf("expected 1 to %d test validators, got %d", len(bonded), len(testValidators))
+	}
+	s := newSanitizer(seed)
+	whiteList := make([]string, len(testValidators))
+	for i, pk := range testValidators {
+		val := bonded[i]
+		s.consKeys[string(val.GetConsAddr())] = pk
+		whiteList[i] = val.OperatorAddress.String()
+	}
+
+	app.prepForZeroHeightGenesis(ctx, whiteList)
+	modules := app.mm.ExportGenesis(ctx)
+	genState := FromMap(app.cdc, modules)
+	s.skipKeylessAccounts(genState)
+	s.sanitize(&genState)
+	for name, bz := range genState.toMap(app.cdc) {
+		if _, ok := modules[name]; ok {
+			modules[name] = bz
+		}
+	}
+	modules[incentive.ModuleName] = adjustIncentiveHeight(ctx, modules[incentive.ModuleName])
+
+	appState, err = codec.MarshalJSONIndent(app.cdc, modules)
+	if err != nil {
+		return nil, nil, mapping, err
+	}
+	validators = staking.WriteValidators(ctx, app.stakingKeeper)
+	for i := range validators {
+		validators[i].PubKey = s.replaceConsPubKey(validators[i].PubKey)
+	}
+	for i, pk := range testValidators {
+		mapping.Validators = append(mapping.Validators, ValidatorMapping{
+			OriginalOperator:      bonded[i].OperatorAddress.String(),
+			ReplacementOperator:   sdk.ValAddress(s.replaceAddress(bonded[i].OperatorAddress)).String(),
+			OriginalConsPubKey:    sdk.MustBech32ifyConsPub(bonded[i].ConsPubKey),
+			ReplacementConsPubKey: sdk.MustBech32ifyConsPub(pk),
+		})
+	}
+	mapping.Accounts = s.accountMappings()
+	return appState, validators, mapping, nil
+}
+
+var (
+	accAddressType  = reflect.TypeOf(sdk.AccAddress{})
+	valAddressType  = reflect.TypeOf(sdk.ValAddress{})
+	consAddressType = reflect.TypeOf(sdk.ConsAddress{})
+	pubKeyType      = reflect.TypeOf((*crypto.PubKey)(nil)).Elem()
+)
+
+// sanitizer replaces the addresses and the consensus pubkeys, the keys of
+// its maps are the bytes of the original addresses
+type sanitizer struct {
+	seed     string
+	accounts map[string]sdk.AccAddress
+	consKeys map[string]crypto.PubKey
+	skipped  map[string]bool
+}
+
+func newSanitizer(seed string) *sanitizer {
+	return &sanitizer{
+		seed:     seed,
+		accounts: make(map[string]sdk.AccAddress),
+		consKeys: make(map[string]crypto.PubKey),
+		skipped:  make(map[string]bool),
+	}
+}
+
+// sanitizedKey returns the test key replacing the address
+func sanitizedKey(seed string, addr []byte) crypto.PrivKey {
+	return secp256k1.GenPrivKeySecp256k1(append([]byte(seed), addr...))
+}
+
+// skipKeylessAccounts keeps the addresses of the accounts without keys, which
+// are hard-coded in the modules: the module accounts, derived from the module
+// names, and the incentive pool
+func (s *sanitizer) skipKeylessAccounts(genState GenesisState) {
+	s.skipped[string(incentive.PoolAddr)] = true
+	for name := range MaccPerms {
+		s.skipped[string(supply.NewModuleAddress(name))] = true
+	}
+	for _, acc := range genState.Accounts {
+		if acc.ModuleName != "" {
+			s.skipped[string(acc.Address)] = true
+		}
+	}
+}
+
+func (s *sanitizer) replaceAddress(addr []byte) []byte {
+	if len(addr) == 0 || s.skipped[string(addr)] {
+		return addr
+	}
+	if replacement, ok := s.accounts[string(addr)]; ok {
+		return replacement
+	}
+	replacement := sdk.AccAddress(sanitizedKey(s.seed, addr).PubKey().Address())
+	s.accounts[string(addr)] = replacement
+	return replacement
+}
+
+func (s *sanitizer) replaceConsAddress(addr []byte) []byte {
+	if pk, ok := s.consKeys[string(addr)]; ok {
+		return pk.Address()
+	}
+	return addr
+}
+
+func (s *sanitizer) replaceConsPubKey(pk crypto.PubKey) crypto.PubKey {
+	if replacement, ok := s.consKeys[string(pk.Address())]; ok {
+		return replacement
+	}
+	return pk
+}
+
+func (s *sanitizer) sanitize(genState *GenesisState) {
+	s.walk(reflect.ValueOf(genState).Elem())
+
+	// the addresses kept in strings
+	genState.AssetData.Whitelist = s.replaceSymbolAddresses(genState.AssetData.Whitelist)
+	genState.AssetData.ForbiddenAddresses = s.replaceSymbolAddresses(genState.AssetData.ForbiddenAddresses)
+	signingInfos := make(map[string]slashing.ValidatorSigningInfo, len(genState.SlashingData.SigningInfos))
+	for key, info := range genState.SlashingData.SigningInfos {
+		signingInfos[s.replaceConsBech32(key)] = info
+	}
+	genState.SlashingData.SigningInfos = signingInfos
+	missedBlocks := make(map[string][]slashing.MissedBlock, len(genState.SlashingData.MissedBlocks))
+	for key, blocks := range genState.SlashingData.MissedBlocks {
+		missedBlocks[s.replaceConsBech32(key)] = blocks
+	}
+	genState.SlashingData.MissedBlocks = missedBlocks
+}
+
+// walk replaces the addresses and the consensus pubkeys in the value, which
+// must be settable
+func (s *sanitizer) walk(v reflect.Value) {
+	switch v.Type() {
+	case accAddressType, valAddressType:
+		v.SetBytes(s.replaceAddress(v.Bytes()))
+		return
+	case consAddressType:
+		v.SetBytes(s.replaceConsAddress(v.Bytes()))
+		return
+	case pubKeyType:
+		if !v.IsNil() {
+			v.Set(reflect.ValueOf(s.replaceConsPubKey(v.Interface().(crypto.PubKey))))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			s.walk(v.Elem())
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			elem := reflect.New(v.Elem().Type()).Elem()
+			elem.Set(v.Elem())
+			s.walk(elem)
+			v.Set(elem)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				s.walk(field)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			s.walk(v.Index(i))
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			s.walk(elem)
+			v.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// replaceSymbolAddresses replaces the addresses of the whitelists and the
+// forbidden addresses of asset, as "symbol:address"
+func (s *sanitizer) replaceSymbolAddresses(entries []string) []string {
+	for i, entry := range entries {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if addr, err := sdk.AccAddressFromBech32(parts[1]); err == nil {
+			entries[i] = parts[0] + ":" + sdk.AccAddress(s.replaceAddress(addr)).String()
+		}
+	}
+	return entries
+}
+
+func (s *sanitizer) replaceConsBech32(key string) string {
+	addr, err := sdk.ConsAddressFromBech32(key)
+	if err != nil {
+		return key
+	}
+	return sdk.ConsAddress(s.replaceConsAddress(addr)).String()
+}
+
+func (s *sanitizer) accountMappings() []AddressMapping {
+	mappings := make([]AddressMapping, 0, len(s.accounts))
+	for addr, replacement := range s.accounts {
+		mappings = append(mappings, AddressMapping{
+			Original:    sdk.AccAddress(addr).String(),
+			Replacement: replacement.String(),
+		})
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].Original < mappings[j].Original
+	})
+	return mappings
+}
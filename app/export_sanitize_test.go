@@ -0,0 +1,109 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/incentive"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestExportSanitized(t *testing.T) {
+	app := startAppWithAccountXAndOrders(t)
+	ctx := app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+	val := app.stakingKeeper.GetBondedValidatorsByPower(ctx)[0]
+	addr := app.marketKeeper.GetAllOrders(ctx)[0].Sender
+	poolCoins := dex.NewCetCoins(1000)
+	require.Nil(t, app.bankKeeper.SendCoins(ctx, addr, incentive.PoolAddr, poolCoins))
+	original, _, err := app.ExportAppStateAndValidators(false, nil)
+	require.Nil(t, err)
+
+	testVal := ed25519.GenPrivKey().PubKey()
+	appState, validators, mapping, err := app.ExportSanitizedAppStateAndValidators("seed", []crypto.PubKey{testVal})
+	require.Nil(t, err)
+
+	// the original addresses are all gone
+	for _, s := range []string{addr.String(), val.OperatorAddress.String(), sdk.MustBech32ifyConsPub(val.ConsPubKey)} {
+		require.Contains(t, string(original), s)
+		require.NotContains(t, string(appState), s)
+	}
+	replacement := sdk.AccAddress(sanitizedKey("seed", addr).PubKey().Address())
+	require.Contains(t, mapping.Accounts, AddressMapping{Original: addr.String(), Replacement: replacement.String()})
+	require.Equal(t, []ValidatorMapping{{
+		OriginalOperator:      val.OperatorAddress.String(),
+		ReplacementOperator:   sdk.ValAddress(sanitizedKey("seed", val.OperatorAddress).PubKey().Address()).String(),
+		OriginalConsPubKey:    sdk.MustBech32ifyConsPub(val.ConsPubKey),
+		ReplacementConsPubKey: sdk.MustBech32ifyConsPub(testVal),
+	}}, mapping.Validators)
+	require.Equal(t, 1, len(validators))
+	require.Equal(t, testVal, validators[0].PubKey)
+
+	// the balances and the orders are kept
+	var genState GenesisState
+	require.Nil(t, app.cdc.UnmarshalJSON(appState, &genState))
+	require.Equal(t, 3, len(genState.MarketData.Orders))
+	for _, order := range genState.MarketData.Orders {
+		require.Equal(t, replacement, order.Sender)
+	}
+	var found, poolFound bool
+	for _, acc := range genState.Accounts {
+		if acc.Address.Equals(replacement) {
+			found = true
+			require.Equal(t, app.accountKeeper.GetAccount(ctx, addr).GetCoins(), acc.Coins)
+		}
+		// the incentive pool has no key, its address is hard-coded
+		if acc.Address.Equals(incentive.PoolAddr) {
+			poolFound = true
+			require.Equal(t, poolCoins, acc.Coins)
+		}
+	}
+	require.True(t, found)
+	require.True(t, poolFound)
+	require.Equal(t, replacement, genState.AuthXData.AccountXs[0].Address)
+	require.Equal(t, testVal, genState.StakingData.Validators[0].ConsPubKey)
+	for key := range genState.SlashingData.SigningInfos {
+		require.Equal(t, sdk.ConsAddress(testVal.Address()).String(), key)
+	}
+
+	_, _, _, err = app.ExportSanitizedAppStateAndValidators("seed", []crypto.PubKey{testVal, ed25519.GenPrivKey().PubKey()})
+	require.NotNil(t, err)
+	_, _, _, err = app.ExportSanitizedAppStateAndValidators("seed", []crypto.PubKey{testVal, testVal})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "duplicate test validator")
+	_, _, _, err = app.ExportSanitizedAppStateAndValidators("seed", nil)
+	require.NotNil(t, err)
+}
+
+func TestExportSanitizedGenesis(t *testing.T) {
+	sk, pk, addr := testutil.KeyPubAddr()
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(cetToken().GetTotalSupply().Int64())}
+	app := startAppWithOneValidator(acc, addr, pk, sk, t)
+
+	testVal := ed25519.GenPrivKey().PubKey()
+	appState, validators, mapping, err := app.ExportSanitizedAppStateAndValidators("seed", []crypto.PubKey{testVal})
+	require.Nil(t, err)
+	require.NotEmpty(t, mapping.Accounts)
+	require.False(t, strings.Contains(string(appState), addr.String()))
+
+	// a test network can start from the sanitized genesis
+	genDoc := &tmtypes.GenesisDoc{
+		GenesisTime: time.Unix(1500000000, 0).UTC(),
+		ChainID:     testChainID,
+		Validators:  validators,
+		AppState:    appState,
+	}
+	require.Nil(t, genDoc.ValidateAndComplete())
+	require.Empty(t, CheckGenesis(genDoc))
+}
@@ -0,0 +1,343 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authexported "github.com/cosmos/cosmos-sdk/x/auth/exported"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/incentive"
+	"github.com/coinexchain/cet-sdk/modules/market"
+)
+
+// The modules' ExportGenesis build their whole genesis in memory, which is
+// too much for the accounts and the orders of a large chain. The streaming
+// export writes these arrays element by element while iterating the stores,
+// and every other module as a whole. The output is the same JSON as the one
+// of `cetd export`, whose keys are sorted, only laid out in lines.
+
+// streamedArray is a large array in the genesis of a module
+type streamedArray struct {
+	// the key of the array in the genesis, empty if the genesis is the array
+	field string
+	cdc   *codec.Codec
+	// the JSON of an empty array, as the module exports it
+	empty string
+	// head returns the genesis of the module without the array
+	head func(app *CetChainApp, ctx sdk.Context) interface{}
+	// iterate calls emit with every element of the array
+	iterate func(app *CetChainApp, ctx sdk.Context, emit func(elem interface{}) error) error
+	// check validates an element and returns its key, which must be unique,
+	// and the key of what it refers to in the head, if any
+	check func(cdc *codec.Codec, bz json.RawMessage) (key, ref string, err error)
+	// checkRefs validates the refs of the elements against the head
+	checkRefs func(cdc *codec.Codec, head map[string]json.RawMessage, refs map[string]struct{}) error
+}
+
+var streamedArrays = map[string]streamedArray{
+	genaccounts.ModuleName: {
+		cdc:   genaccounts.ModuleCdc,
+		empty: "[]",
+		iterate: func(app *CetChainApp, ctx sdk.Context, emit func(interface{}) error) (err error) {
+			app.accountKeeper.IterateAccounts(ctx, func(acc authexported.Account) bool {
+				var account genaccounts.GenesisAccount
+				if account, err = genaccounts.NewGenesisAccountI(acc); err == nil {
+					err = emit(account)
+				}
+				return err != nil
+			})
+			return
+		},
+		check: func(cdc *codec.Codec, bz json.RawMessage) (string, string, error) {
+			var acc genaccounts.GenesisAccount
+			if err := unmarshalElement(cdc, bz, &acc); err != nil {
+				return "", "", err
+			}
+			return acc.Address.String(), "", genaccounts.ValidateGenesis(genaccounts.GenesisState{acc})
+		},
+	},
+	authx.ModuleName: {
+		field: "accountxs",
+		cdc:   authx.ModuleCdc,
+		empty: "null",
+		head: func(app *CetChainApp, ctx sdk.Context) interface{} {
+			return authx.NewGenesisState(app.accountXKeeper.GetParams(ctx), nil)
+		},
+		iterate: func(app *CetChainApp, ctx sdk.Context, emit func(interface{}) error) (err error) {
+			app.accountXKeeper.IterateAccounts(ctx, func(accx authx.AccountX) bool {
+				err = emit(accx)
+				return err != nil
+			})
+			return
+		},
+		check: func(cdc *codec.Codec, bz json.RawMessage) (string, string, error) {
+			var accx authx.AccountX
+			if err := unmarshalElement(cdc, bz, &accx); err != nil {
+				return "", "", err
+			}
+			if accx.Address.Empty() {
+				return "", "", errors.New("nil accountX found in genesis state")
+			}
+			return accx.Address.String(), "", nil
+		},
+	},
+	market.ModuleName: {
+		field: "orders",
+		cdc:   market.ModuleCdc,
+		empty: "null",
+		head: func(app *CetChainApp, ctx sdk.Context) interface{} {
+			k := app.marketKeeper
+			return market.NewGenesisState(k.GetParams(ctx), nil, k.GetAllMarketInfos(ctx), k.GetOrderCleanTime(ctx))
+		},
+		iterate: func(app *CetChainApp, ctx sdk.Context, emit func(interface{}) error) error {
+			iter := sdk.KVStorePrefixIterator(ctx.KVStore(app.keyMarket), marketOrderPrefix)
+			defer iter.Close()
+			for ; iter.Valid(); iter.Next() {
+				order := &market.Order{}
+				if err := app.cdc.UnmarshalBinaryBare(iter.Value(), order); err != nil {
+					return err
+				}
+				if err := emit(order); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		check: func(cdc *codec.Codec, bz json.RawMessage) (string, string, error) {
+			var order market.Order
+			if err := unmarshalElement(cdc, bz, &order); err != nil {
+				return "", "", err
+			}
+			return order.OrderID(), order.TradingPair, nil
+		},
+		// not checked by the ValidateGenesis of market, but InitGenesis can
+		// not add the orders of an unknown trading pair
+		checkRefs: func(cdc *codec.Codec, head map[string]json.RawMessage, pairs map[string]struct{}) error {
+			var infos []market.MarketInfo
+			if bz, ok := head["market_infos"]; ok {
+				if err := cdc.UnmarshalJSON(bz, &infos); err != nil {
+					return err
+				}
+			}
+			symbols := make(map[string]struct{}, len(infos))
+			for _, info := range infos {
+				symbols[info.GetSymbol()] = struct{}{}
+			}
+			for pair := range pairs {
+				if _, ok := symbols[pair]; !ok {
+					return fmt.Errorf("orders of the unknown trading pair %s", pair)
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// StreamGenesis writes the genesis doc of the exported state to w, as
+// ExportAppStateAndValidators does, but without building the app state in
+// memory. The fields of genDoc other than the validators and the app state
+// are kept.
+func (app *CetChainApp) StreamGenesis(w io.Writer, genDoc *tmtypes.GenesisDoc,
+	forZeroHeight bool, jailWhiteList []string) error {
+
+	// as if they could withdraw from the start of the next block
+	ctx := app.NewContext(true, abci.Header{Height: app.LastBlockHeight()})
+
+	if forZeroHeight {
+		app.prepForZeroHeightGenesis(ctx, jailWhiteList)
+	}
+
+	doc := *genDoc
+	doc.Validators = staking.WriteValidators(ctx, app.stakingKeeper)
+	doc.AppState = nil
+	fields, err := sortedJSONFields(app.cdc, doc)
+	if err != nil {
+		return err
+	}
+	fields["app_state"] = nil
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("{")
+	for i, key := range sortedFieldKeys(fields) {
+		if i > 0 {
+			bw.WriteString(",")
+		}
+		fmt.Fprintf(bw, "\n%q: ", key)
+		if key == "app_state" {
+			err = app.writeAppState(bw, ctx, forZeroHeight)
+		} else {
+			_, err = bw.Write(fields[key])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	bw.WriteString("\n}\n")
+	return bw.Flush()
+}
+
+func (app *CetChainApp) writeAppState(w *bufio.Writer, ctx sdk.Context, forZeroHeight bool) error {
+	names := append([]string(nil), app.mm.OrderExportGenesis...)
+	sort.Strings(names)
+
+	w.WriteString("{")
+	for i, name := range names {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		fmt.Fprintf(w, "\n%q: ", name)
+		var err error
+		if array, ok := streamedArrays[name]; ok {
+			err = array.write(w, app, ctx)
+		} else {
+			bz := app.mm.Modules[name].ExportGenesis(ctx)
+			if name == incentive.ModuleName && forZeroHeight {
+				bz = adjustIncentiveHeight(ctx, bz)
+			}
+			err = writeSortedJSON(w, bz)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %v", name, err)
+		}
+	}
+	_, err := w.WriteString("\n}")
+	return err
+}
+
+func (array streamedArray) write(w *bufio.Writer, app *CetChainApp, ctx sdk.Context) error {
+	// the JSON of the head before and after the array
+	var before, after []byte
+	if array.head != nil {
+		head, err := sortJSON(array.cdc, array.head(app, ctx))
+		if err != nil {
+			return err
+		}
+		hole := []byte(fmt.Sprintf("%q:null", array.field))
+		i := bytes.Index(head, hole)
+		if i < 0 {
+			return fmt.Errorf("%s not found in the genesis", array.field)
+		}
+		before = head[:i+len(hole)-len("null")]
+		after = head[i+len(hole):]
+	}
+
+	w.Write(before)
+	n := 0
+	err := array.iterate(app, ctx, func(elem interface{}) error {
+		bz, err := marshalElement(array.cdc, elem)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			w.WriteString("[\n")
+		} else {
+			w.WriteString(",\n")
+		}
+		n++
+		_, err = w.Write(bz)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		w.WriteString(array.empty)
+	} else {
+		w.WriteString("\n]")
+	}
+	_, err = w.Write(after)
+	return err
+}
+
+// marshalElement returns the JSON of elem as an element of an array, amino
+// wraps the registered types with their names only at the top level
+func marshalElement(cdc *codec.Codec, elem interface{}) ([]byte, error) {
+	v := reflect.ValueOf(elem)
+	array := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+	array.Index(0).Set(v)
+	bz, err := sortJSON(cdc, array.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return bz[1 : len(bz)-1], nil
+}
+
+// unmarshalElement decodes the JSON of an element of an array into ptr
+func unmarshalElement(cdc *codec.Codec, bz json.RawMessage, ptr interface{}) error {
+	v := reflect.ValueOf(ptr).Elem()
+	array := reflect.New(reflect.SliceOf(v.Type()))
+	if err := cdc.UnmarshalJSON(append(append([]byte("["), bz...), ']'), array.Interface()); err != nil {
+		return err
+	}
+	if array.Elem().Len() != 1 {
+		return errors.New("not an element")
+	}
+	v.Set(array.Elem().Index(0))
+	return nil
+}
+
+// adjustIncentiveHeight makes the incentive of a zero height genesis continue
+// from the exported height
+func adjustIncentiveHeight(ctx sdk.Context, bz json.RawMessage) json.RawMessage {
+	var ig incentive.GenesisState
+	incentive.ModuleCdc.MustUnmarshalJSON(bz, &ig)
+	ig.State.HeightAdjustment = ig.State.HeightAdjustment + ctx.BlockHeader().Height
+	return incentive.ModuleCdc.MustMarshalJSON(ig)
+}
+
+// sortJSON returns the amino JSON of v with sorted keys, as `cetd export`
+// writes it
+func sortJSON(cdc *codec.Codec, v interface{}) ([]byte, error) {
+	bz, err := cdc.MarshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return sdk.SortJSON(bz)
+}
+
+// writeSortedJSON writes the genesis of a module with sorted keys, a module
+// without genesis, e.g. distrx, is null as in the JSON of a map
+func writeSortedJSON(w io.Writer, bz json.RawMessage) error {
+	if len(bz) == 0 {
+		bz = json.RawMessage("null")
+	}
+	bz, err := sdk.SortJSON(bz)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bz)
+	return err
+}
+
+func sortedJSONFields(cdc *codec.Codec, v interface{}) (map[string]json.RawMessage, error) {
+	bz, err := sortJSON(cdc, v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(bz, &fields)
+	return fields, err
+}
+
+func sortedFieldKeys(fields map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
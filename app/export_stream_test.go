@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func startAppWithAccountXAndOrders(t *testing.T) *CetChainApp {
+	sk, pk, addr := testutil.KeyPubAddr()
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(cetToken().GetTotalSupply().Int64())}
+	app := startAppWithOneValidator(acc, addr, pk, sk, t)
+
+	header := abci.Header{Height: app.LastBlockHeight() + 1}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	ctx := app.NewContext(false, header)
+	app.accountXKeeper.SetAccountX(ctx, authx.AccountX{Address: addr, MemoRequired: true})
+	for seq := uint64(1); seq <= 3; seq++ {
+		err := app.marketKeeper.SetOrder(ctx, &market.Order{
+			Sender:      addr,
+			Sequence:    seq,
+			TradingPair: "abc/cet",
+			OrderType:   market.LimitOrder,
+			Price:       sdk.NewDec(int64(seq)),
+			Quantity:    100,
+			Side:        market.BUY,
+			TimeInForce: market.GTE,
+			Height:      header.Height,
+			LeftStock:   100,
+			Freeze:      100 * int64(seq),
+		})
+		require.Nil(t, err)
+	}
+	app.EndBlock(abci.RequestEndBlock{Height: header.Height})
+	app.Commit()
+	return app
+}
+
+// startAppWithMarketAndOrders also adds the market of the orders, which the
+// streaming validator checks
+func startAppWithMarketAndOrders(t *testing.T) *CetChainApp {
+	app := startAppWithAccountXAndOrders(t)
+	header := abci.Header{Height: app.LastBlockHeight() + 1}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	ctx := app.NewContext(false, header)
+	require.Nil(t, app.marketKeeper.SetMarket(ctx, market.MarketInfo{
+		Stock: "abc", Money: dex.CET, PricePrecision: 8, LastExecutedPrice: sdk.NewDec(2),
+	}))
+	app.EndBlock(abci.RequestEndBlock{Height: header.Height})
+	app.Commit()
+	return app
+}
+
+func streamGenesis(t *testing.T, app *CetChainApp, forZeroHeight bool) []byte {
+	genDoc := &tmtypes.GenesisDoc{
+		GenesisTime: time.Unix(1500000000, 0).UTC(),
+		ChainID:     testChainID,
+	}
+	var buf bytes.Buffer
+	require.Nil(t, app.StreamGenesis(&buf, genDoc, forZeroHeight, nil))
+	return buf.Bytes()
+}
+
+func TestStreamGenesis(t *testing.T) {
+	app := startAppWithMarketAndOrders(t)
+	streamed := streamGenesis(t, app, false)
+
+	appState, validators, err := app.ExportAppStateAndValidators(false, nil)
+	require.Nil(t, err)
+
+	var doc struct {
+		AppState   json.RawMessage `json:"app_state"`
+		Validators json.RawMessage `json:"validators"`
+	}
+	require.Nil(t, json.Unmarshal(streamed, &doc))
+	require.Equal(t, string(sdk.MustSortJSON(appState)), string(sdk.MustSortJSON(doc.AppState)))
+	require.Equal(t, string(sdk.MustSortJSON(app.cdc.MustMarshalJSON(validators))),
+		string(sdk.MustSortJSON(doc.Validators)))
+
+	var genState GenesisState
+	require.Nil(t, app.cdc.UnmarshalJSON(doc.AppState, &genState))
+	require.Equal(t, 1, len(genState.AuthXData.AccountXs))
+	require.Equal(t, 3, len(genState.MarketData.Orders))
+
+	// one element per line
+	require.Equal(t, 3, strings.Count(string(streamed), "\n{\"deal_money\""))
+
+	require.Nil(t, ValidateGenesisStream(bytes.NewReader(streamed)))
+	require.Nil(t, ValidateGenesisStream(bytes.NewReader(streamGenesis(t, app, true))))
+}
+
+func TestValidateGenesisStream(t *testing.T) {
+	app := startAppWithMarketAndOrders(t)
+	streamed := string(streamGenesis(t, app, false))
+
+	// duplicate the first order
+	lines := strings.Split(streamed, "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, `"orders":[`) {
+			lines = append(lines[:i+1], append([]string{lines[i+1]}, lines[i+1:]...)...)
+			break
+		}
+	}
+	err := ValidateGenesisStream(strings.NewReader(strings.Join(lines, "\n")))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "duplicate element")
+
+	// the orders of a trading pair not in the market_infos
+	err = ValidateGenesisStream(strings.NewReader(strings.Replace(streamed, `"stock":"abc"`, `"stock":"xyz"`, 1)))
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "unknown trading pair abc/cet")
+
+	err = ValidateGenesisStream(strings.NewReader(strings.Replace(streamed, `"chain_id": "`+testChainID+`"`, `"chain_id": ""`, 1)))
+	require.NotNil(t, err)
+
+	err = ValidateGenesisStream(strings.NewReader(streamed[:len(streamed)/2]))
+	require.NotNil(t, err)
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GenesisTable is an entity of the genesis state as a flat table
+type GenesisTable struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// GenesisTables flattens the entities of the genesis state into tables. The
+// columns are fixed, except the ones of the balances of the accounts, which
+// are the denominations of the tokens and the accounts in sorted order.
+func GenesisTables(genState GenesisState) []GenesisTable {
+	return []GenesisTable{
+		accountsTable(genState),
+		lockedCoinsTable(genState),
+		tokensTable(genState),
+		ordersTable(genState),
+		marketsTable(genState),
+		bancorsTable(genState),
+		delegationsTable(genState),
+		unbondingEntriesTable(genState),
+	}
+}
+
+func accountsTable(genState GenesisState) GenesisTable {
+	denomSet := make(map[string]bool)
+	for _, token := range genState.AssetData.Tokens {
+		denomSet[token.GetSymbol()] = true
+	}
+	for _, acc := range genState.Accounts {
+		for _, coin := range acc.Coins {
+			denomSet[coin.Denom] = true
+		}
+	}
+	denoms := make([]string, 0, len(denomSet))
+	for denom := range denomSet {
+		denoms = append(denoms, denom)
+	}
+	sort.Strings(denoms)
+
+	table := GenesisTable{
+		Name: "accounts",
+		Header: append([]string{"address", "account_number", "sequence", "module_name",
+			"original_vesting", "vesting_start_time", "vesting_end_time"}, denoms...),
+	}
+	for _, acc := range genState.Accounts {
+		row := []string{
+			acc.Address.String(),
+			strconv.FormatUint(acc.AccountNumber, 10),
+			strconv.FormatUint(acc.Sequence, 10),
+			acc.ModuleName,
+			acc.OriginalVesting.String(),
+			strconv.FormatInt(acc.StartTime, 10),
+			strconv.FormatInt(acc.EndTime, 10),
+		}
+		for _, denom := range denoms {
+			row = append(row, acc.Coins.AmountOf(denom).String())
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+func lockedCoinsTable(genState GenesisState) GenesisTable {
+	table := GenesisTable{
+		Name:   "accountx_locked_coins",
+		Header: []string{"address", "denom", "amount", "unlock_time", "from_address", "supervisor", "reward"},
+	}
+	for _, accx := range genState.AuthXData.AccountXs {
+		for _, lc := range accx.LockedCoins {
+			table.Rows = append(table.Rows, []string{
+				accx.Address.String(),
+				lc.Coin.Denom,
+				lc.Coin.Amount.String(),
+				strconv.FormatInt(lc.UnlockTime, 10),
+				lc.FromAddress.String(),
+				lc.Supervisor.String(),
+				strconv.FormatInt(lc.Reward, 10),
+			})
+		}
+	}
+	return table
+}
+
+func tokensTable(genState GenesisState) GenesisTable {
+	table := GenesisTable{
+		Name: "tokens",
+		Header: []string{"symbol", "name", "owner", "total_supply", "send_lock", "total_burn", "total_mint",
+			"mintable", "burnable", "addr_forbiddable", "token_forbiddable", "is_forbidden",
+			"url", "description", "identity"},
+	}
+	for _, token := range genState.AssetData.Tokens {
+		table.Rows = append(table.Rows, []string{
+			token.GetSymbol(),
+			token.GetName(),
+			token.GetOwner().String(),
+			token.GetTotalSupply().String(),
+			token.GetSendLock().String(),
+			token.GetTotalBurn().String(),
+			token.GetTotalMint().String(),
+			strconv.FormatBool(token.GetMintable()),
+			strconv.FormatBool(token.GetBurnable()),
+			strconv.FormatBool(token.GetAddrForbiddable()),
+			strconv.FormatBool(token.GetTokenForbiddable()),
+			strconv.FormatBool(token.GetIsForbidden()),
+			token.GetURL(),
+			token.GetDescription(),
+			token.GetIdentity(),
+		})
+	}
+	return table
+}
+
+func ordersTable(genState GenesisState) GenesisTable {
+	table := GenesisTable{
+		Name: "orders",
+		Header: []string{"order_id", "sender", "sequence", "trading_pair", "order_type", "side", "price",
+			"quantity", "time_in_force", "height", "exist_blocks", "frozen_commission", "frozen_feature_fee",
+			"left_stock", "freeze", "deal_stock", "deal_money"},
+	}
+	for _, order := range genState.MarketData.Orders {
+		table.Rows = append(table.Rows, []string{
+			order.OrderID(),
+			order.Sender.String(),
+			strconv.FormatUint(order.Sequence, 10),
+			order.TradingPair,
+			strconv.Itoa(int(order.OrderType)),
+			strconv.Itoa(int(order.Side)),
+			order.Price.String(),
+			strconv.FormatInt(order.Quantity, 10),
+			strconv.FormatInt(order.TimeInForce, 10),
+			strconv.FormatInt(order.Height, 10),
+			strconv.FormatInt(order.ExistBlocks, 10),
+			strconv.FormatInt(order.FrozenCommission, 10),
+			strconv.FormatInt(order.FrozenFeatureFee, 10),
+			strconv.FormatInt(order.LeftStock, 10),
+			strconv.FormatInt(order.Freeze, 10),
+			strconv.FormatInt(order.DealStock, 10),
+			strconv.FormatInt(order.DealMoney, 10),
+		})
+	}
+	return table
+}
+
+func marketsTable(genState GenesisState) GenesisTable {
+	table := GenesisTable{
+		Name:   "markets",
+		Header: []string{"symbol", "stock", "money", "price_precision", "order_precision", "last_executed_price"},
+	}
+	for _, info := range genState.MarketData.MarketInfos {
+		table.Rows = append(table.Rows, []string{
+			info.GetSymbol(),
+			info.Stock,
+			info.Money,
+			strconv.Itoa(int(info.PricePrecision)),
+			strconv.Itoa(int(info.OrderPrecision)),
+			info.LastExecutedPrice.String(),
+		})
+	}
+	return table
+}
+
+func bancorsTable(genState GenesisState) GenesisTable {
+	table := GenesisTable{
+		Name: "bancors",
+		Header: []string{"symbol", "owner", "stock", "money", "init_price", "max_supply", "stock_precision",
+			"max_price", "max_money", "ar", "price", "stock_in_pool", "money_in_pool", "earliest_cancel_time"},
+	}
+	symbols := make([]string, 0, len(genState.BancorData.BancorInfoMap))
+	for symbol := range genState.BancorData.BancorInfoMap {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		bi := genState.BancorData.BancorInfoMap[symbol]
+		table.Rows = append(table.Rows, []string{
+			symbol,
+			bi.Owner.String(),
+			bi.Stock,
+			bi.Money,
+			bi.InitPrice.String(),
+			bi.MaxSupply.String(),
+			strconv.Itoa(int(bi.StockPrecision)),
+			bi.MaxPrice.String(),
+			bi.MaxMoney.String(),
+			strconv.FormatInt(bi.AR, 10),
+			bi.Price.String(),
+			bi.StockInPool.String(),
+			bi.MoneyInPool.String(),
+			strconv.FormatInt(bi.EarliestCancelTime, 10),
+		})
+	}
+	return table
+}
+
+func delegationsTable(genState GenesisState) GenesisTable {
+	table := GenesisTable{
+		Name:   "delegations",
+		Header: []string{"delegator", "validator", "shares"},
+	}
+	for _, del := range genState.StakingData.Delegations {
+		table.Rows = append(table.Rows, []string{
+			del.DelegatorAddress.String(),
+			del.ValidatorAddress.String(),
+			del.Shares.String(),
+		})
+	}
+	return table
+}
+
+func unbondingEntriesTable(genState GenesisState) GenesisTable {
+	table := GenesisTable{
+		Name:   "unbonding_entries",
+		Header: []string{"delegator", "validator", "creation_height", "completion_time", "initial_balance", "balance"},
+	}
+	for _, ubd := range genState.StakingData.UnbondingDelegations {
+		for _, entry := range ubd.Entries {
+			table.Rows = append(table.Rows, []string{
+				ubd.DelegatorAddress.String(),
+				ubd.ValidatorAddress.String(),
+				strconv.FormatInt(entry.CreationHeight, 10),
+				entry.CompletionTime.UTC().Format(time.RFC3339Nano),
+				entry.InitialBalance.String(),
+				entry.Balance.String(),
+			})
+		}
+	}
+	return table
+}
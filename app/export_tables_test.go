@@ -0,0 +1,85 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func findTable(t *testing.T, tables []GenesisTable, name string) GenesisTable {
+	for _, table := range tables {
+		if table.Name == name {
+			for _, row := range table.Rows {
+				require.Equal(t, len(table.Header), len(row), name)
+			}
+			return table
+		}
+	}
+	require.Fail(t, "no table "+name)
+	return GenesisTable{}
+}
+
+func TestGenesisTables(t *testing.T) {
+	app := startAppWithAccountXAndOrders(t)
+	genState := app.ExportGenesisState(app.NewContext(true, abci.Header{Height: app.LastBlockHeight()}))
+	addr := genState.MarketData.Orders[0].Sender
+	genState.AuthXData.AccountXs[0].LockedCoins = authx.LockedCoins{
+		authx.NewLockedCoin(dex.CET, sdk.NewInt(100), 1600000000),
+	}
+	genState.MarketData.MarketInfos = append(genState.MarketData.MarketInfos, market.MarketInfo{
+		Stock: "abc", Money: dex.CET, PricePrecision: 8, LastExecutedPrice: sdk.NewDec(2),
+	})
+	genState.BancorData.BancorInfoMap["abc/cet"] = bancorlite.BancorInfo{
+		Owner: addr, Stock: "abc", Money: dex.CET, InitPrice: sdk.NewDec(1), MaxSupply: sdk.NewInt(1000),
+		MaxPrice: sdk.NewDec(10), MaxMoney: sdk.ZeroInt(), Price: sdk.NewDec(1),
+		StockInPool: sdk.NewInt(1000), MoneyInPool: sdk.ZeroInt(),
+	}
+	tables := GenesisTables(genState)
+	require.Equal(t, 8, len(tables))
+
+	accounts := findTable(t, tables, "accounts")
+	require.Equal(t, []string{"address", "account_number", "sequence", "module_name",
+		"original_vesting", "vesting_start_time", "vesting_end_time", dex.CET}, accounts.Header)
+	require.Equal(t, len(genState.Accounts), len(accounts.Rows))
+	for i, acc := range genState.Accounts {
+		require.Equal(t, acc.Address.String(), accounts.Rows[i][0])
+		require.Equal(t, acc.Coins.AmountOf(dex.CET).String(), accounts.Rows[i][7])
+	}
+
+	lockedCoins := findTable(t, tables, "accountx_locked_coins")
+	require.Equal(t, [][]string{{addr.String(), dex.CET, "100", "1600000000", "", "", "0"}}, lockedCoins.Rows)
+
+	tokens := findTable(t, tables, "tokens")
+	require.Equal(t, 1, len(tokens.Rows))
+	require.Equal(t, dex.CET, tokens.Rows[0][0])
+
+	orders := findTable(t, tables, "orders")
+	require.Equal(t, 3, len(orders.Rows))
+	require.Equal(t, genState.MarketData.Orders[0].OrderID(), orders.Rows[0][0])
+	require.Equal(t, "abc/cet", orders.Rows[0][3])
+	require.Equal(t, "100", orders.Rows[0][14])
+
+	markets := findTable(t, tables, "markets")
+	require.Equal(t, [][]string{{"abc/cet", "abc", dex.CET, "8", "0", sdk.NewDec(2).String()}}, markets.Rows)
+
+	bancors := findTable(t, tables, "bancors")
+	require.Equal(t, 1, len(bancors.Rows))
+	require.Equal(t, []string{"abc/cet", addr.String(), "abc", dex.CET}, bancors.Rows[0][:4])
+
+	delegations := findTable(t, tables, "delegations")
+	require.Equal(t, len(genState.StakingData.Delegations), len(delegations.Rows))
+	require.Equal(t, 1, len(delegations.Rows))
+
+	unbonding := findTable(t, tables, "unbonding_entries")
+	require.Empty(t, unbonding.Rows)
+	require.Equal(t, 6, len(unbonding.Header))
+}
@@ -0,0 +1,258 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	authexported "github.com/cosmos/cosmos-sdk/x/auth/exported"
+	"github.com/cosmos/cosmos-sdk/x/crisis"
+	"github.com/cosmos/cosmos-sdk/x/genutil"
+	"github.com/cosmos/cosmos-sdk/x/params"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+	supplyexported "github.com/cosmos/cosmos-sdk/x/supply/exported"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// CheckGenesis loads the genesis doc into an in-memory app and checks the
+// state it starts with: the genesis of every module, every registered
+// invariant and the cross-module checks below. It returns all the violations
+// found, not only the first one.
+func CheckGenesis(genDoc *tmtypes.GenesisDoc) (violations []string) {
+	if violations = validateModules(genDoc); len(violations) > 0 {
+		// InitChain would panic on them
+		return violations
+	}
+
+	app := NewCetChainApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, 0)
+	if err := initChainFromGenesis(app, genDoc); err != nil {
+		return []string{fmt.Sprintf("init chain: %v", err)}
+	}
+	ctx := app.NewContext(false, abci.Header{Height: app.LastBlockHeight() + 1, Time: genDoc.GenesisTime})
+
+	// before the invariants, as pre-total-supply of authx resets the module
+	// account of authx
+	checks := []func(sdk.Context) []string{
+		app.checkSupply,
+		app.checkModuleAccounts,
+		app.checkFrozenCoins,
+	}
+	for _, check := range checks {
+		violations = append(violations, recoverViolations(func() []string { return check(ctx) })...)
+	}
+	for _, route := range app.crisisKeeper.Routes() {
+		route := route
+		violations = append(violations, recoverViolations(func() []string {
+			if msg, broken := route.Invar(ctx); broken {
+				return []string{fmt.Sprintf("invariant %s/%s broken: %s", route.ModuleName, route.Route, strings.TrimSpace(msg))}
+			}
+			return nil
+		})...)
+	}
+	return violations
+}
+
+// validateModules runs the ValidateGenesis of every module
+func validateModules(genDoc *tmtypes.GenesisDoc) (violations []string) {
+	var genState map[string]json.RawMessage
+	if err := json.Unmarshal(genDoc.AppState, &genState); err != nil {
+		return []string{fmt.Sprintf("invalid app state: %v", err)}
+	}
+	names := make([]string, 0, len(ModuleBasics.BasicManager))
+	for name := range ModuleBasics.BasicManager {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == genutil.ModuleName && len(genState[name]) == 0 {
+			continue
+		}
+		if err := ModuleBasics.BasicManager[name].ValidateGenesis(genState[name]); err != nil {
+			violations = append(violations, fmt.Sprintf("invalid genesis of %s: %v", name, err))
+		}
+	}
+	return violations
+}
+
+func initChainFromGenesis(app *CetChainApp, genDoc *tmtypes.GenesisDoc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	validators := make([]*tmtypes.Validator, len(genDoc.Validators))
+	for i, val := range genDoc.Validators {
+		validators[i] = tmtypes.NewValidator(val.PubKey, val.Power)
+	}
+	var updates []abci.ValidatorUpdate
+	if len(validators) > 0 {
+		updates = tmtypes.TM2PB.ValidatorUpdates(tmtypes.NewValidatorSet(validators))
+	}
+
+	// crisis asserts the invariants in InitGenesis, which panics on the first
+	// one broken, so they are left out and asserted later one by one
+	invariants := app.crisisKeeper
+	paramSpace := params.NewKeeper(app.cdc, app.keyParams, app.tkeyParams, params.DefaultCodespace).
+		Subspace(crisis.DefaultParamspace)
+	app.crisisKeeper = crisis.NewKeeper(paramSpace, 0, app.supplyKeeper, auth.FeeCollectorName)
+	defer func() { app.crisisKeeper = invariants }()
+
+	app.InitChain(abci.RequestInitChain{
+		Time:          genDoc.GenesisTime,
+		ChainId:       genDoc.ChainID,
+		Validators:    updates,
+		AppStateBytes: genDoc.AppState,
+	})
+	return nil
+}
+
+func recoverViolations(check func() []string) (violations []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			violations = []string{fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+	return check()
+}
+
+// checkSupply compares the total supply with the coins of all the accounts,
+// including the module accounts
+func (app *CetChainApp) checkSupply(ctx sdk.Context) []string {
+	var total sdk.Coins
+	app.accountKeeper.IterateAccounts(ctx, func(acc authexported.Account) bool {
+		total = total.Add(acc.GetCoins())
+		return false
+	})
+	supplyTotal := app.supplyKeeper.GetSupply(ctx).GetTotal()
+	if !coinsEqual(total, supplyTotal) {
+		return []string{fmt.Sprintf("total supply is %s, but the accounts hold %s", coinsString(supplyTotal), coinsString(total))}
+	}
+	return nil
+}
+
+// checkModuleAccounts checks the module accounts are of the right type and
+// permissions, and the coins of authx are the locked and frozen coins of the
+// accounts. The balances of the other module accounts are checked by the
+// invariants of their modules.
+func (app *CetChainApp) checkModuleAccounts(ctx sdk.Context) (violations []string) {
+	names := make([]string, 0, len(MaccPerms))
+	for name := range MaccPerms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		acc := app.accountKeeper.GetAccount(ctx, supply.NewModuleAddress(name))
+		if acc == nil {
+			continue
+		}
+		macc, ok := acc.(supplyexported.ModuleAccountI)
+		if !ok {
+			violations = append(violations, fmt.Sprintf("account of module %s is not a module account", name))
+			continue
+		}
+		if !samePermissions(macc.GetPermissions(), MaccPerms[name]) {
+			violations = append(violations, fmt.Sprintf("module account %s has permissions %v, expected %v",
+				name, macc.GetPermissions(), MaccPerms[name]))
+		}
+	}
+
+	var expected sdk.Coins
+	app.accountXKeeper.IterateAccounts(ctx, func(accx authx.AccountX) bool {
+		expected = expected.Add(accx.GetAllCoins())
+		return false
+	})
+	var coins sdk.Coins
+	if acc := app.accountKeeper.GetAccount(ctx, supply.NewModuleAddress(authx.ModuleName)); acc != nil {
+		coins = acc.GetCoins()
+	}
+	if !coinsEqual(coins, expected) {
+		violations = append(violations, fmt.Sprintf(
+			"module account %s holds %s, but the accounts have %s locked and frozen", authx.ModuleName, coinsString(coins), coinsString(expected)))
+	}
+	return violations
+}
+
+// coinsEqual does not panic as Coins.IsEqual on different denoms
+func coinsEqual(a, b sdk.Coins) bool {
+	return a.IsAllGTE(b) && b.IsAllGTE(a)
+}
+
+func coinsString(coins sdk.Coins) string {
+	if coins.Empty() {
+		return "0"
+	}
+	return coins.String()
+}
+
+func samePermissions(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	perms := make(map[string]bool, len(a))
+	for _, p := range a {
+		perms[p] = true
+	}
+	for _, p := range b {
+		if !perms[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkFrozenCoins compares the frozen coins of every account with the funds
+// of its orders and the reserves of its bancors, which are all the coins
+// frozen by market and bancorlite
+func (app *CetChainApp) checkFrozenCoins(ctx sdk.Context) (violations []string) {
+	expected := make(map[string]sdk.Coins)
+	for _, order := range app.marketKeeper.GetAllOrders(ctx) {
+		coins := dex.NewCoins(order.GetOrderUsedDenom(), order.Freeze).
+			Add(dex.NewCetCoins(order.FrozenCommission + order.FrozenFeatureFee))
+		addr := order.Sender.String()
+		expected[addr] = expected[addr].Add(coins)
+	}
+	for _, bi := range app.bancorKeeper.GetAllBancorInfos(ctx) {
+		coins := sdk.NewCoins(sdk.NewCoin(bi.Stock, bi.StockInPool), sdk.NewCoin(bi.Money, bi.MoneyInPool))
+		addr := bi.Owner.String()
+		expected[addr] = expected[addr].Add(coins)
+	}
+
+	frozen := make(map[string]sdk.Coins)
+	app.accountXKeeper.IterateAccounts(ctx, func(accx authx.AccountX) bool {
+		if !accx.FrozenCoins.IsZero() {
+			frozen[accx.Address.String()] = accx.FrozenCoins
+		}
+		return false
+	})
+
+	addrs := make(map[string]bool, len(expected)+len(frozen))
+	for addr := range expected {
+		addrs[addr] = true
+	}
+	for addr := range frozen {
+		addrs[addr] = true
+	}
+	sorted := make([]string, 0, len(addrs))
+	for addr := range addrs {
+		sorted = append(sorted, addr)
+	}
+	sort.Strings(sorted)
+	for _, addr := range sorted {
+		if !coinsEqual(frozen[addr], expected[addr]) {
+			violations = append(violations, fmt.Sprintf(
+				"account %s has %s frozen, but its orders and bancors freeze %s", addr, coinsString(frozen[addr]), coinsString(expected[addr])))
+		}
+	}
+	return violations
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func exportGenesisDoc(t *testing.T, app *CetChainApp, edit func(*GenesisState)) *tmtypes.GenesisDoc {
+	appState, validators, err := app.ExportAppStateAndValidators(false, nil)
+	require.Nil(t, err)
+	if edit != nil {
+		var genState GenesisState
+		require.Nil(t, app.cdc.UnmarshalJSON(appState, &genState))
+		edit(&genState)
+		appState = app.cdc.MustMarshalJSON(genState)
+	}
+	return &tmtypes.GenesisDoc{
+		GenesisTime: time.Unix(1500000000, 0).UTC(),
+		ChainID:     testChainID,
+		Validators:  validators,
+		AppState:    appState,
+	}
+}
+
+func TestCheckGenesis(t *testing.T) {
+	sk, pk, addr := testutil.KeyPubAddr()
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(cetToken().GetTotalSupply().Int64())}
+	app := startAppWithOneValidator(acc, addr, pk, sk, t)
+
+	require.Empty(t, CheckGenesis(exportGenesisDoc(t, app, nil)))
+
+	// all the violations are reported
+	violations := CheckGenesis(exportGenesisDoc(t, app, func(genState *GenesisState) {
+		genState.Supply.Supply = genState.Supply.Supply.Add(dex.NewCetCoins(1))
+		genState.AuthXData.AccountXs = append(genState.AuthXData.AccountXs,
+			authx.AccountX{Address: addr, FrozenCoins: dex.NewCetCoins(100)})
+	}))
+	require.True(t, len(violations) >= 3, strings.Join(violations, "\n"))
+	require.Contains(t, violations[0], "total supply is")
+	require.Contains(t, strings.Join(violations, "\n"), "module account authx holds")
+	require.Contains(t, strings.Join(violations, "\n"), "but its orders and bancors freeze")
+	require.Contains(t, strings.Join(violations, "\n"), "invariant supply/total-supply broken")
+
+	violations = CheckGenesis(exportGenesisDoc(t, app, func(genState *GenesisState) {
+		genState.AuthXData.Params.MinGasPriceLimit = sdk.NewDec(-1)
+		genState.MarketData.Params.MarketFeeRate = -1
+	}))
+	require.Equal(t, 2, len(violations), strings.Join(violations, "\n"))
+	require.Contains(t, violations[0], "invalid genesis of authx")
+	require.Contains(t, violations[1], "invalid genesis of market")
+}
+
+func TestCheckGenesisOrders(t *testing.T) {
+	app := startAppWithAccountXAndOrders(t)
+
+	// the orders set by the keeper do not freeze coins
+	violations := CheckGenesis(exportGenesisDoc(t, app, nil))
+	require.Equal(t, 1, len(violations), strings.Join(violations, "\n"))
+	require.Contains(t, violations[0], "has 0 frozen, but its orders and bancors freeze 600cet")
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"sync"
+
+	abcicli "github.com/tendermint/tendermint/abci/client"
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/proxy"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+var _ proxy.ClientCreator = localClientCreator{}
+
+// localClientCreator is the same as proxy.NewLocalClientCreator, except that
+// the lock on the app is shared with the local query client of the embedded LCD
+type localClientCreator struct {
+	mtx *sync.Mutex
+	app abci.Application
+}
+
+func (c localClientCreator) NewABCIClient() (abcicli.Client, error) {
+	return abcicli.NewLocalClient(c.mtx, c.app), nil
+}
+
+// NewLocalClientCreator must be used to connect the app to an in-process
+// Tendermint node when the embedded trade-server is enabled
+func (app *CetChainApp) NewLocalClientCreator() proxy.ClientCreator {
+	return localClientCreator{mtx: &app.abciMtx, app: app}
+}
+
+var _ rpcclient.Client = (*localRPCClient)(nil)
+
+// localRPCClient serves ABCI queries directly by the running app, and
+// forwards the other calls (broadcasts, blocks, ...) to the in-process
+// node's client, so no loopback HTTP connection is needed.
+type localRPCClient struct {
+	rpcclient.Client
+	app *CetChainApp
+}
+
+func newLocalRPCClient(app *CetChainApp, nodeClient rpcclient.Client) *localRPCClient {
+	return &localRPCClient{Client: nodeClient, app: app}
+}
+
+func (c *localRPCClient) ABCIInfo() (*ctypes.ResultABCIInfo, error) {
+	c.app.abciMtx.Lock()
+	defer c.app.abciMtx.Unlock()
+	res := c.app.Info(proxy.RequestInfo)
+	return &ctypes.ResultABCIInfo{Response: res}, nil
+}
+
+func (c *localRPCClient) ABCIQuery(path string, data cmn.HexBytes) (*ctypes.ResultABCIQuery, error) {
+	return c.ABCIQueryWithOptions(path, data, rpcclient.DefaultABCIQueryOptions)
+}
+
+func (c *localRPCClient) ABCIQueryWithOptions(path string, data cmn.HexBytes,
+	opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+
+	c.app.abciMtx.Lock()
+	defer c.app.abciMtx.Unlock()
+	res := c.app.Query(abci.RequestQuery{
+		Path:   path,
+		Data:   data,
+		Height: opts.Height,
+		Prove:  opts.Prove,
+	})
+	return &ctypes.ResultABCIQuery{Response: res}, nil
+}
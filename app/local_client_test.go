@@ -0,0 +1,35 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/proxy"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/version"
+)
+
+func TestLocalRPCClient(t *testing.T) {
+	app := NewCetChainApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, 0)
+	ctx := app.newCLIContextForEmbeddedLDC(nil)
+	node, err := ctx.GetNode()
+	require.Nil(t, err)
+
+	res, err := node.ABCIQuery("/app/version", nil)
+	require.Nil(t, err)
+	require.True(t, res.Response.IsOK())
+	require.Equal(t, version.Version, string(res.Response.Value))
+
+	info, err := node.ABCIInfo()
+	require.Nil(t, err)
+	require.Equal(t, version.Version, info.Response.Version)
+
+	creator := app.NewLocalClientCreator()
+	abciClient, err := creator.NewABCIClient()
+	require.Nil(t, err)
+	resInfo, err := abciClient.InfoSync(proxy.RequestInfo)
+	require.Nil(t, err)
+	require.Equal(t, info.Response, *resInfo)
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cosmos/cosmos-sdk/x/auth"
+)
+
+const (
+	// MetricsSubsystem is a subsystem shared by all metrics exposed by the app.
+	MetricsSubsystem = "app"
+)
+
+// the causes of CheckTx rejections
+const (
+	RejectCausePlugin           = "plugin"
+	RejectCauseUnconfirmedLimit = "unconfirmed_limit"
+	RejectCauseDecode           = "decode"
+	RejectCauseAnte             = "ante"
+)
+
+// Metrics contains metrics exposed by CetChainApp.
+// They are served by the Prometheus listener of Tendermint.
+type Metrics struct {
+	// Number of delivered msgs, labeled by module, msg_type and result code.
+	DeliveredMsgs metrics.Counter
+	// Number of txs rejected by CheckTx, labeled by cause.
+	CheckTxRejections metrics.Counter
+	// Number of published messages, labeled by key.
+	PubMsgs metrics.Counter
+	// Number of published messages of a block.
+	PubMsgsPerBlock metrics.Histogram
+	// Number of accounts tracked by the unconfirmed tx limiter.
+	UnconfirmedLimiterSize metrics.Gauge
+	// Time spent in beginBlocker, in seconds.
+	BeginBlockerSeconds metrics.Histogram
+	// Time spent in endBlocker, in seconds.
+	EndBlockerSeconds metrics.Histogram
+	// Time spent in Commit, in seconds.
+	CommitSeconds metrics.Histogram
+	// Time spent in a module's BeginBlock or EndBlock, labeled by phase and module.
+	ModuleBlockerSeconds metrics.Histogram
+	// Number of store reads of a module's BeginBlock or EndBlock, labeled by phase and module.
+	ModuleStoreReads metrics.Counter
+	// Number of store writes of a module's BeginBlock or EndBlock, labeled by phase and module.
+	ModuleStoreWrites metrics.Counter
+
+	// set by NopMetrics, the modules are not profiled unless slow blocks are logged
+	nop bool
+}
+
+// PrometheusMetrics returns Metrics build using Prometheus client library.
+// Optionally, labels can be provided along with their values ("foo",
+// "fooValue").
+func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
+	labels := []string{}
+	for i := 0; i < len(labelsAndValues); i += 2 {
+		labels = append(labels, labelsAndValues[i])
+	}
+	return &Metrics{
+		DeliveredMsgs: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "delivered_msgs",
+			Help:      "Number of delivered msgs.",
+		}, appendLabels(labels, "module", "msg_type", "code")).With(labelsAndValues...),
+		CheckTxRejections: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "check_tx_rejections",
+			Help:      "Number of txs rejected by CheckTx.",
+		}, appendLabels(labels, "cause")).With(labelsAndValues...),
+		PubMsgs: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "pub_msgs",
+			Help:      "Number of published messages.",
+		}, appendLabels(labels, "key")).With(labelsAndValues...),
+		PubMsgsPerBlock: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "pub_msgs_per_block",
+			Help:      "Number of published messages of a block.",
+			Buckets:   stdprometheus.ExponentialBuckets(1, 4, 10),
+		}, labels).With(labelsAndValues...),
+		UnconfirmedLimiterSize: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "unconfirmed_limiter_size",
+			Help:      "Number of accounts tracked by the unconfirmed tx limiter.",
+		}, labels).With(labelsAndValues...),
+		BeginBlockerSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "begin_blocker_seconds",
+			Help:      "Time spent in BeginBlock.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, labels).With(labelsAndValues...),
+		EndBlockerSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "end_blocker_seconds",
+			Help:      "Time spent in EndBlock.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, labels).With(labelsAndValues...),
+		CommitSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "commit_seconds",
+			Help:      "Time spent in Commit.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, labels).With(labelsAndValues...),
+		ModuleBlockerSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "module_blocker_seconds",
+			Help:      "Time spent in a module's BeginBlock or EndBlock.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.0001, 2, 16),
+		}, appendLabels(labels, "phase", "module")).With(labelsAndValues...),
+		ModuleStoreReads: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "module_store_reads",
+			Help:      "Number of store reads of a module's BeginBlock or EndBlock.",
+		}, appendLabels(labels, "phase", "module")).With(labelsAndValues...),
+		ModuleStoreWrites: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "module_store_writes",
+			Help:      "Number of store writes of a module's BeginBlock or EndBlock.",
+		}, appendLabels(labels, "phase", "module")).With(labelsAndValues...),
+	}
+}
+
+// the returned slice never shares its backing array with labels
+func appendLabels(labels []string, extra ...string) []string {
+	return append(append(make([]string, 0, len(labels)+len(extra)), labels...), extra...)
+}
+
+// NopMetrics returns no-op Metrics.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		DeliveredMsgs:          discard.NewCounter(),
+		CheckTxRejections:      discard.NewCounter(),
+		PubMsgs:                discard.NewCounter(),
+		PubMsgsPerBlock:        discard.NewHistogram(),
+		UnconfirmedLimiterSize: discard.NewGauge(),
+		BeginBlockerSeconds:    discard.NewHistogram(),
+		EndBlockerSeconds:      discard.NewHistogram(),
+		CommitSeconds:          discard.NewHistogram(),
+		ModuleBlockerSeconds:   discard.NewHistogram(),
+		ModuleStoreReads:       discard.NewCounter(),
+		ModuleStoreWrites:      discard.NewCounter(),
+		nop:                    true,
+	}
+}
+
+// SetMetrics replaces the no-op metrics, it must be called before the app is started
+func (app *CetChainApp) SetMetrics(m *Metrics) {
+	app.metrics = m
+}
+
+func (app *CetChainApp) observeDuration(h metrics.Histogram, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+func (app *CetChainApp) countDeliveredMsgs(stdTx auth.StdTx, formatOK bool, code uint32) {
+	codeLabel := strconv.FormatUint(uint64(code), 10)
+	if !formatOK {
+		app.metrics.DeliveredMsgs.With("module", "unknown", "msg_type", "unknown", "code", codeLabel).Add(1)
+		return
+	}
+	for _, msg := range stdTx.Msgs {
+		app.metrics.DeliveredMsgs.With("module", msg.Route(), "msg_type", msg.Type(), "code", codeLabel).Add(1)
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func gatherMetric(t *testing.T, name string) []*dto.Metric {
+	families, err := stdprometheus.DefaultGatherer.Gather()
+	require.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()
+		}
+	}
+	return nil
+}
+
+func labelsOf(m *dto.Metric) map[string]string {
+	labels := make(map[string]string)
+	for _, pair := range m.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
+
+func TestMetrics(t *testing.T) {
+	_, _, toAddr := testutil.KeyPubAddr()
+	key, _, fromAddr := testutil.KeyPubAddr()
+	coins := sdk.NewCoins(sdk.NewInt64Coin("cet", 30000000000))
+	app := initAppWithBaseAccounts(auth.BaseAccount{Address: fromAddr, Coins: coins})
+	app.SetMetrics(PrometheusMetrics("metrics_test"))
+
+	header := abci.Header{Height: 1, Time: time.Now()}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+
+	// rejected by the ante handler, the sequence is wrong
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1000000000), 0)
+	badTx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 5, key).Build()
+	badTxBytes, _ := auth.DefaultTxEncoder(app.cdc)(badTx)
+	require.False(t, app.CheckTx(abci.RequestCheckTx{Tx: badTxBytes}).IsOK())
+
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	txBytes, _ := auth.DefaultTxEncoder(app.cdc)(tx)
+	require.True(t, app.DeliverTx(abci.RequestDeliverTx{Tx: txBytes}).IsOK())
+
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	delivered := gatherMetric(t, "metrics_test_app_delivered_msgs")
+	require.Equal(t, 1, len(delivered))
+	require.Equal(t, map[string]string{"module": "bankx", "msg_type": "send", "code": "0"},
+		labelsOf(delivered[0]))
+	require.Equal(t, 1.0, delivered[0].GetCounter().GetValue())
+
+	rejections := gatherMetric(t, "metrics_test_app_check_tx_rejections")
+	require.Equal(t, 1, len(rejections))
+	require.Equal(t, RejectCauseAnte, labelsOf(rejections[0])["cause"])
+
+	for _, name := range []string{"begin_blocker_seconds", "end_blocker_seconds", "commit_seconds"} {
+		histograms := gatherMetric(t, "metrics_test_app_"+name)
+		require.Equal(t, 1, len(histograms), name)
+		require.Equal(t, uint64(1), histograms[0].GetHistogram().GetSampleCount(), name)
+	}
+}
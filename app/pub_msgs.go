@@ -9,6 +9,10 @@ import (
 type PubMsg struct {
 	Key   []byte
 	Value []byte
+
+	// used by the subscription filters, not published
+	addresses []string
+	msgTypes  []string
 }
 
 func collectKafkaEvents(events []abci.Event, app *CetChainApp) []abci.Event {
@@ -1,13 +1,18 @@
 package app
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/libs/common"
+	tmtypes "github.com/tendermint/tendermint/types"
 
+	"github.com/coinexchain/cet-sdk/modules/bankx"
 	"github.com/coinexchain/cet-sdk/msgqueue"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
 )
 
 func TestCollectKafkaEvents(t *testing.T) {
@@ -52,3 +57,36 @@ func TestDiscardKafkaEvents(t *testing.T) {
 	require.Equal(t, "other", events[0].Type)
 	require.Equal(t, "other", events[1].Type)
 }
+
+func TestNotificationTxJSON(t *testing.T) {
+	key, _, fromAddr := testutil.KeyPubAddr()
+	_, _, toAddr := testutil.KeyPubAddr()
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1000000000), 0)
+	stdTx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 1, key).Build()
+	req := abci.RequestDeliverTx{Tx: []byte("tx")}
+
+	app := &CetChainApp{txCount: 7, height: 100}
+	app.appendNotificationTx(req, stdTx, abci.ResponseDeliverTx{}, nil)
+	require.Equal(t, 1, len(app.pubMsgs))
+
+	// the payload read by the trade-server
+	txJSON, err := json.Marshal(&stdTx)
+	require.Nil(t, err)
+	expected, err := json.Marshal(&NotificationTx{
+		Signers:      stdTx.GetSigners(),
+		SerialNumber: 7,
+		TxJSON:       string(txJSON),
+		MsgTypes:     []string{"MsgSend"},
+		Height:       100,
+		Hash:         tmtypes.Tx(req.Tx).Hash(),
+	})
+	require.Nil(t, err)
+	require.Equal(t, string(expected), string(app.pubMsgs[0].Value))
+
+	var n4s NotificationTx
+	require.Nil(t, json.Unmarshal(app.pubMsgs[0].Value, &n4s))
+	var tx map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(n4s.TxJSON), &tx))
+	require.Equal(t, 1, len(tx["msg"].([]interface{})))
+}
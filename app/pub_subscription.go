@@ -0,0 +1,187 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	toml "github.com/pelletier/go-toml"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/libs/log"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+
+	"github.com/coinexchain/cet-sdk/msgqueue"
+)
+
+// FlagPubSubscription is the path of the subscription config file, relative
+// paths are resolved against the node's home directory
+const FlagPubSubscription = "pub-subscription"
+
+// The key sent after all the messages of a block, every sink receives it
+const pubMsgKeyCommit = "commit"
+
+// PubSink declares which pub messages are written to one broker. Empty lists
+// do not filter. Addresses and MsgTypes are only checked for the messages that
+// carry such information, e.g. notify_tx.
+type PubSink struct {
+	Broker    string   `toml:"broker"`
+	Keys      []string `toml:"keys"`
+	Addresses []string `toml:"addresses"`
+	MsgTypes  []string `toml:"msg_types"`
+}
+
+type PubSubscriptionConfig struct {
+	Sinks []PubSink `toml:"sink"`
+}
+
+func LoadPubSubscriptionConfig(filePath string) (*PubSubscriptionConfig, error) {
+	tree, err := toml.LoadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	conf := &PubSubscriptionConfig{}
+	if err = tree.Unmarshal(conf); err != nil {
+		return nil, err
+	}
+	if len(conf.Sinks) == 0 {
+		return nil, fmt.Errorf("no sink is declared in %s", filePath)
+	}
+	for _, sink := range conf.Sinks {
+		if len(sink.Broker) == 0 {
+			return nil, fmt.Errorf("sink without broker in %s", filePath)
+		}
+	}
+	return conf, nil
+}
+
+type stringSet map[string]struct{}
+
+func newStringSet(list []string) stringSet {
+	set := make(stringSet, len(list))
+	for _, s := range list {
+		set[s] = struct{}{}
+	}
+	return set
+}
+
+// an empty set matches everything
+func (set stringSet) matchAny(list []string) bool {
+	if len(set) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if _, ok := set[s]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+type pubSink struct {
+	sender    msgqueue.MsgSender
+	keys      stringSet
+	addresses stringSet
+	msgTypes  stringSet
+}
+
+func (sink *pubSink) wantsKey(key string) bool {
+	return key == pubMsgKeyCommit || sink.keys.matchAny([]string{key})
+}
+
+func (sink *pubSink) accept(msg PubMsg) bool {
+	if !sink.wantsKey(string(msg.Key)) {
+		return false
+	}
+	if len(msg.addresses) != 0 && !sink.addresses.matchAny(msg.addresses) {
+		return false
+	}
+	if len(msg.msgTypes) != 0 && !sink.msgTypes.matchAny(msg.msgTypes) {
+		return false
+	}
+	return true
+}
+
+var _ msgqueue.MsgSender = (*pubMsgRouter)(nil)
+
+// pubMsgRouter dispatches the pub messages to the sinks declared in the
+// subscription config, each sink has its own producer.
+type pubMsgRouter struct {
+	sinks []*pubSink
+}
+
+func newPubMsgRouter(conf *PubSubscriptionConfig, logger log.Logger) *pubMsgRouter {
+	topics := viper.GetString(msgqueue.FlagTopics)
+	featureToggle := viper.GetBool(msgqueue.FlagFeatureToggle)
+	router := &pubMsgRouter{sinks: make([]*pubSink, 0, len(conf.Sinks))}
+	for _, s := range conf.Sinks {
+		router.sinks = append(router.sinks, &pubSink{
+			sender:    msgqueue.NewProducerFromConfig([]string{s.Broker}, topics, featureToggle, logger),
+			keys:      newStringSet(s.Keys),
+			addresses: newStringSet(s.Addresses),
+			msgTypes:  newStringSet(s.MsgTypes),
+		})
+	}
+	return router
+}
+
+// wantsKey returns true if any sink publishes the messages with this key
+func (router *pubMsgRouter) wantsKey(key string) bool {
+	for _, sink := range router.sinks {
+		if sink.wantsKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (router *pubMsgRouter) sendPubMsg(msg PubMsg) {
+	for _, sink := range router.sinks {
+		if sink.accept(msg) {
+			sink.sender.SendMsg(msg.Key, msg.Value)
+		}
+	}
+}
+
+func (router *pubMsgRouter) SendMsg(key []byte, v []byte) {
+	router.sendPubMsg(PubMsg{Key: key, Value: v})
+}
+
+func (router *pubMsgRouter) IsSubscribed(topic string) bool {
+	for _, sink := range router.sinks {
+		if sink.sender.IsSubscribed(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func (router *pubMsgRouter) IsOpenToggle() bool {
+	for _, sink := range router.sinks {
+		if sink.sender.IsOpenToggle() {
+			return true
+		}
+	}
+	return false
+}
+
+func (router *pubMsgRouter) GetMode() []string {
+	modes := make([]string, 0, len(router.sinks))
+	for _, sink := range router.sinks {
+		modes = append(modes, sink.sender.GetMode()...)
+	}
+	return modes
+}
+
+func (router *pubMsgRouter) Close() {
+	for _, sink := range router.sinks {
+		sink.sender.Close()
+	}
+}
+
+func getPubSubscriptionFile() string {
+	filePath := viper.GetString(FlagPubSubscription)
+	if len(filePath) == 0 || filepath.IsAbs(filePath) {
+		return filePath
+	}
+	return filepath.Join(viper.GetString(flags.FlagHome), filePath)
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/common"
+
+	"github.com/coinexchain/cet-sdk/msgqueue"
+)
+
+type recordingSender struct {
+	keys []string
+}
+
+func (s *recordingSender) SendMsg(key []byte, v []byte)   { s.keys = append(s.keys, string(key)) }
+func (s *recordingSender) IsSubscribed(topic string) bool { return topic == "market" }
+func (s *recordingSender) IsOpenToggle() bool             { return true }
+func (s *recordingSender) GetMode() []string              { return []string{"recording"} }
+func (s *recordingSender) Close()                         {}
+
+func abciEventWithKeys(keys ...string) abci.Event {
+	event := abci.Event{Type: msgqueue.EventTypeMsgQueue}
+	for _, key := range keys {
+		event.Attributes = append(event.Attributes, common.KVPair{Key: []byte(key), Value: []byte("{}")})
+	}
+	return event
+}
+
+func TestLoadPubSubscriptionConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pub-subscription")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filePath := filepath.Join(dir, "pub-subscription.toml")
+	err = ioutil.WriteFile(filePath, []byte(`
+[[sink]]
+broker = "nop"
+keys = ["notify_tx", "height_info"]
+msg_types = ["MsgSend"]
+
+[[sink]]
+broker = "os:stdout"
+`), 0644)
+	require.Nil(t, err)
+	conf, err := LoadPubSubscriptionConfig(filePath)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(conf.Sinks))
+	require.Equal(t, "nop", conf.Sinks[0].Broker)
+	require.Equal(t, []string{"notify_tx", "height_info"}, conf.Sinks[0].Keys)
+	require.Equal(t, []string{"MsgSend"}, conf.Sinks[0].MsgTypes)
+	require.Equal(t, 0, len(conf.Sinks[1].Keys))
+
+	err = ioutil.WriteFile(filePath, []byte("[[sink]]\nkeys = [\"slash\"]\n"), 0644)
+	require.Nil(t, err)
+	_, err = LoadPubSubscriptionConfig(filePath)
+	require.NotNil(t, err)
+}
+
+func TestPubMsgRouter(t *testing.T) {
+	txSender, allSender := &recordingSender{}, &recordingSender{}
+	router := &pubMsgRouter{sinks: []*pubSink{
+		{
+			sender:    txSender,
+			keys:      newStringSet([]string{"notify_tx"}),
+			addresses: newStringSet([]string{"coinex1alice"}),
+			msgTypes:  newStringSet(nil),
+		},
+		{
+			sender:    allSender,
+			keys:      newStringSet(nil),
+			addresses: newStringSet(nil),
+			msgTypes:  newStringSet([]string{"MsgSend"}),
+		},
+	}}
+
+	require.True(t, router.wantsKey("notify_tx"))
+	require.True(t, router.wantsKey("slash"))
+	require.True(t, router.IsSubscribed("market"))
+
+	router.sendPubMsg(PubMsg{Key: []byte("notify_tx"), addresses: []string{"coinex1bob"}, msgTypes: []string{"MsgSend"}})
+	router.sendPubMsg(PubMsg{Key: []byte("notify_tx"), addresses: []string{"coinex1alice"}, msgTypes: []string{"MsgVote"}})
+	router.sendPubMsg(PubMsg{Key: []byte("slash")})
+	router.SendMsg([]byte(pubMsgKeyCommit), []byte("{}"))
+
+	require.Equal(t, []string{"notify_tx", "commit"}, txSender.keys)
+	require.Equal(t, []string{"notify_tx", "slash", "commit"}, allSender.keys)
+
+	router.sinks = router.sinks[:1]
+	require.False(t, router.wantsKey("slash"))
+	app := &CetChainApp{pubRouter: router}
+	app.appendPubEvent(abciEventWithKeys("slash", "notify_tx"))
+	require.Equal(t, 1, len(app.pubMsgs))
+	require.Equal(t, "notify_tx", string(app.pubMsgs[0].Key))
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/tendermint/iavl"
+	"github.com/tendermint/tendermint/libs/log"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// see cosmos-sdk/store/rootmulti/store.go and store/iavl/store.go
+const (
+	latestVersionKey = "s/latest"
+	commitInfoKeyFmt = "s/%d"
+	storeKeyPrefix   = "s/k:%s/"
+	iavlCacheSize    = 10000
+)
+
+// RollbackResult is what RollbackStores rolled back
+type RollbackResult struct {
+	FromHeight int64
+	ToHeight   int64
+	AppHash    []byte
+}
+
+// RollbackStores resets the versions of the mounted stores in the application
+// db to the given height and deletes the later versions, so the next block to
+// commit is height+1. Every version from the height to the latest one must be
+// kept by the pruning, or else nothing is changed.
+func RollbackStores(db dbm.DB, height int64) (RollbackResult, error) {
+	app := NewCetChainApp(log.NewNopLogger(), db, nil, true, 0)
+	result := RollbackResult{FromHeight: app.LastBlockHeight(), ToHeight: height}
+	if height <= tmtypes.GenesisBlockHeight || height >= result.FromHeight {
+		return result, fmt.Errorf("height %d is not between the genesis height %d and the latest height %d",
+			height, tmtypes.GenesisBlockHeight, result.FromHeight)
+	}
+	version := height - tmtypes.GenesisBlockHeight
+	latestVersion := result.FromHeight - tmtypes.GenesisBlockHeight
+
+	keys := app.kvStoreKeys()
+	trees := make([]*iavl.MutableTree, len(keys))
+	for i, key := range keys {
+		trees[i] = iavl.NewMutableTree(dbm.NewPrefixDB(db, []byte(fmt.Sprintf(storeKeyPrefix, key.Name()))), iavlCacheSize)
+		if _, err := trees[i].Load(); err != nil {
+			return result, fmt.Errorf("error loading store %s: %v", key.Name(), err)
+		}
+		// the later versions are deleted one by one, so none of them may be pruned
+		horizon := latestVersion
+		for horizon > 0 && trees[i].VersionExists(horizon-1) {
+			horizon--
+		}
+		if version < horizon {
+			return result, fmt.Errorf("height %d is below the pruning horizon %d of store %s",
+				height, horizon+tmtypes.GenesisBlockHeight, key.Name())
+		}
+	}
+
+	for i, tree := range trees {
+		if _, err := tree.LoadVersionForOverwriting(version); err != nil {
+			return result, fmt.Errorf("error rolling back store %s: %v", keys[i].Name(), err)
+		}
+		if tree.Version() != version {
+			return result, fmt.Errorf("store %s is at version %d after the rollback", keys[i].Name(), tree.Version())
+		}
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	for v := version + 1; v <= latestVersion; v++ {
+		batch.Delete([]byte(fmt.Sprintf(commitInfoKeyFmt, v)))
+	}
+	batch.Set([]byte(latestVersionKey), app.cdc.MustMarshalBinaryLengthPrefixed(version))
+	batch.WriteSync()
+
+	rolledBack := NewCetChainApp(log.NewNopLogger(), db, nil, true, 0)
+	if rolledBack.LastBlockHeight() != height {
+		return result, fmt.Errorf("the latest height is %d after the rollback", rolledBack.LastBlockHeight())
+	}
+	result.AppHash = rolledBack.LastCommitID().Hash
+	return result, nil
+}
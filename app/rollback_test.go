@@ -0,0 +1,75 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	bam "github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/store"
+	storetypes "github.com/cosmos/cosmos-sdk/store/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func startAppWithBlocks(db dbm.DB, pruning store.PruningOptions, blocks int, addr sdk.AccAddress) *CetChainApp {
+	app := NewCetChainApp(log.NewNopLogger(), db, nil, true, 0, bam.SetPruning(pruning))
+	genState := NewDefaultGenesisState()
+	genState.AssetData.Tokens = append(genState.AssetData.Tokens, cetToken())
+	genState.StakingData.Params.BondDenom = dex.DefaultBondDenom
+	addGenesisAccounts(&genState, auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1000)})
+	app.InitChain(abci.RequestInitChain{ChainId: testChainID, AppStateBytes: app.cdc.MustMarshalJSON(genState)})
+	for i := 0; i < blocks; i++ {
+		commitBlock(app, func(ctx sdk.Context) {})
+	}
+	return app
+}
+
+func TestRollbackStores(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	db := dbm.NewMemDB()
+	app := startAppWithBlocks(db, store.PruneNothing, 2, addr)
+	hashes := app.StoreHashes()
+	appHash := app.LastCommitID().Hash
+	commitBlock(app, func(ctx sdk.Context) {
+		app.accountXKeeper.SetAccountX(ctx, authx.AccountX{Address: addr, MemoRequired: true})
+	})
+	commitBlock(app, func(ctx sdk.Context) {})
+
+	_, err := RollbackStores(db, 4)
+	require.NotNil(t, err)
+	result, err := RollbackStores(db, 2)
+	require.Nil(t, err)
+	require.Equal(t, RollbackResult{FromHeight: 4, ToHeight: 2, AppHash: appHash}, result)
+
+	// the node continues from the rolled back height
+	app = NewCetChainApp(log.NewNopLogger(), db, nil, true, 0, bam.SetPruning(store.PruneNothing))
+	require.Equal(t, int64(2), app.LastBlockHeight())
+	require.Equal(t, hashes, app.StoreHashes())
+	_, found := app.accountXKeeper.GetAccountX(app.NewContext(true, abci.Header{}), addr)
+	require.False(t, found)
+	commitBlock(app, func(ctx sdk.Context) {})
+	require.Equal(t, int64(3), app.LastBlockHeight())
+	require.Nil(t, NewCetChainApp(log.NewNopLogger(), db, nil, false, 0).LoadHeight(3))
+	require.NotNil(t, NewCetChainApp(log.NewNopLogger(), db, nil, false, 0).LoadHeight(4))
+}
+
+func TestRollbackStoresBelowPruningHorizon(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	db := dbm.NewMemDB()
+	startAppWithBlocks(db, storetypes.NewPruningOptions(1, 2), 5, addr)
+
+	_, err := RollbackStores(db, 2)
+	require.Contains(t, err.Error(), "below the pruning horizon 4")
+	result, err := RollbackStores(db, 4)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), result.FromHeight)
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/params"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+	supplyexported "github.com/cosmos/cosmos-sdk/x/supply/exported"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	"github.com/coinexchain/cet-sdk/modules/market"
+)
+
+// the types of the changes of a store key
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// StoreChange is a key of a KV store which differs between two versions of the
+// state. The values are decoded into JSON when the layout of the store is known,
+// or else they are hex strings.
+type StoreChange struct {
+	Store string          `json:"store"`
+	Key   string          `json:"key"`
+	Type  string          `json:"type"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+}
+
+// storeLayout is how the values under a key prefix of a store are encoded
+type storeLayout struct {
+	prefix         []byte
+	lengthPrefixed bool
+	newValue       func() interface{}
+}
+
+// the key prefixes of the cet-sdk modules, which are not exported by their
+// keepers, see the internal/keepers of the modules
+var (
+	authxAccountPrefix = []byte{0x01}
+	assetTokenPrefix   = []byte{0x01}
+	// OrderBookKeyPrefix and a zero byte
+	marketOrderPrefix = []byte{0x11, 0x0}
+	marketInfoPrefix  = []byte{0x15}
+	bancorInfoPrefix  = []byte{0x10}
+)
+
+var storeLayouts = map[string][]storeLayout{
+	auth.StoreKey: {
+		{prefix: auth.AddressStoreKeyPrefix, newValue: func() interface{} { var acc auth.Account; return &acc }},
+	},
+	authx.StoreKey: {
+		{prefix: authxAccountPrefix, newValue: func() interface{} { return &authx.AccountX{} }},
+	},
+	supply.StoreKey: {
+		{prefix: supply.SupplyKey, lengthPrefixed: true,
+			newValue: func() interface{} { var s supplyexported.SupplyI; return &s }},
+	},
+	staking.StoreKey: {
+		{prefix: staking.ValidatorsKey, lengthPrefixed: true, newValue: func() interface{} { return &staking.Validator{} }},
+		{prefix: staking.DelegationKey, lengthPrefixed: true, newValue: func() interface{} { return &staking.Delegation{} }},
+		{prefix: staking.UnbondingDelegationKey, lengthPrefixed: true,
+			newValue: func() interface{} { return &staking.UnbondingDelegation{} }},
+	},
+	asset.StoreKey: {
+		{prefix: assetTokenPrefix, newValue: func() interface{} { var token asset.Token; return &token }},
+	},
+	market.StoreKey: {
+		{prefix: marketOrderPrefix, newValue: func() interface{} { return &market.Order{} }},
+		{prefix: marketInfoPrefix, newValue: func() interface{} { return &market.MarketInfo{} }},
+	},
+	bancorlite.StoreKey: {
+		{prefix: bancorInfoPrefix, newValue: func() interface{} { return &bancorlite.BancorInfo{} }},
+	},
+}
+
+// StateDiff walks every mounted KV store of the loaded versions of the state of
+// from and to in key order, and calls fn for each key added, removed or modified
+// by to, until fn returns true
+func StateDiff(from, to *CetChainApp, fn func(change StoreChange) (stop bool)) {
+	fromCtx := from.NewContext(true, abci.Header{Height: from.LastBlockHeight()})
+	toCtx := to.NewContext(true, abci.Header{Height: to.LastBlockHeight()})
+	toKeys := to.kvStoreKeys()
+	for i, key := range from.kvStoreKeys() {
+		if diffStore(to, key.Name(), fromCtx.KVStore(key), toCtx.KVStore(toKeys[i]), fn) {
+			return
+		}
+	}
+}
+
+func diffStore(app *CetChainApp, name string, fromStore, toStore sdk.KVStore, fn func(StoreChange) bool) bool {
+	fromIter := fromStore.Iterator(nil, nil)
+	defer fromIter.Close()
+	toIter := toStore.Iterator(nil, nil)
+	defer toIter.Close()
+
+	for fromIter.Valid() || toIter.Valid() {
+		var change StoreChange
+		cmp := 0
+		if !fromIter.Valid() {
+			cmp = 1
+		} else if !toIter.Valid() {
+			cmp = -1
+		} else {
+			cmp = bytes.Compare(fromIter.Key(), toIter.Key())
+		}
+		switch {
+		case cmp < 0:
+			change = StoreChange{Key: hex.EncodeToString(fromIter.Key()), Type: ChangeRemoved,
+				From: app.decodeStoreValue(name, fromIter.Key(), fromIter.Value())}
+			fromIter.Next()
+		case cmp > 0:
+			change = StoreChange{Key: hex.EncodeToString(toIter.Key()), Type: ChangeAdded,
+				To: app.decodeStoreValue(name, toIter.Key(), toIter.Value())}
+			toIter.Next()
+		default:
+			if !bytes.Equal(fromIter.Value(), toIter.Value()) {
+				change = StoreChange{Key: hex.EncodeToString(toIter.Key()), Type: ChangeModified,
+					From: app.decodeStoreValue(name, fromIter.Key(), fromIter.Value()),
+					To:   app.decodeStoreValue(name, toIter.Key(), toIter.Value())}
+			}
+			fromIter.Next()
+			toIter.Next()
+		}
+		if change.Type == "" {
+			continue
+		}
+		change.Store = name
+		if fn(change) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeStoreValue decodes the value of a key through the app codec, falling
+// back to the hex string of the value when the layout of the key is unknown
+func (app *CetChainApp) decodeStoreValue(store string, key, value []byte) json.RawMessage {
+	if store == params.StoreKey && json.Valid(value) {
+		return value
+	}
+	for _, layout := range storeLayouts[store] {
+		if !bytes.HasPrefix(key, layout.prefix) {
+			continue
+		}
+		ptr := layout.newValue()
+		var err error
+		if layout.lengthPrefixed {
+			err = app.cdc.UnmarshalBinaryLengthPrefixed(value, ptr)
+		} else {
+			err = app.cdc.UnmarshalBinaryBare(value, ptr)
+		}
+		if err != nil {
+			break
+		}
+		if bz, err := app.cdc.MarshalJSON(ptr); err == nil {
+			return bz
+		}
+		break
+	}
+	bz, _ := json.Marshal(hex.EncodeToString(value))
+	return bz
+}
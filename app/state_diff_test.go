@@ -0,0 +1,105 @@
+package app
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	bam "github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/store"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func commitBlock(app *CetChainApp, deliver func(ctx sdk.Context)) {
+	header := abci.Header{Height: app.LastBlockHeight() + 1}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	deliver(app.NewContext(false, header))
+	app.EndBlock(abci.RequestEndBlock{Height: header.Height})
+	app.Commit()
+}
+
+func collectStateDiff(t *testing.T, db dbm.DB, fromHeight, toHeight int64) map[string]StoreChange {
+	from := NewCetChainApp(log.NewNopLogger(), db, nil, false, 0)
+	require.Nil(t, from.LoadHeight(fromHeight))
+	to := NewCetChainApp(log.NewNopLogger(), db, nil, false, 0)
+	require.Nil(t, to.LoadHeight(toHeight))
+
+	changes := make(map[string]StoreChange)
+	StateDiff(from, to, func(change StoreChange) bool {
+		changes[change.Store+"/"+change.Key] = change
+		return false
+	})
+	return changes
+}
+
+func TestStateDiff(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	db := dbm.NewMemDB()
+	app := NewCetChainApp(log.NewNopLogger(), db, nil, true, 0, bam.SetPruning(store.PruneNothing))
+	genState := NewDefaultGenesisState()
+	genState.AssetData.Tokens = append(genState.AssetData.Tokens, cetToken())
+	genState.StakingData.Params.BondDenom = dex.DefaultBondDenom
+	addGenesisAccounts(&genState, auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1000)})
+	app.InitChain(abci.RequestInitChain{ChainId: testChainID, AppStateBytes: app.cdc.MustMarshalJSON(genState)})
+
+	info := market.MarketInfo{Stock: "abc", Money: dex.CET, PricePrecision: 8, LastExecutedPrice: sdk.NewDec(2)}
+	commitBlock(app, func(ctx sdk.Context) {})
+	commitBlock(app, func(ctx sdk.Context) {
+		app.accountXKeeper.SetAccountX(ctx, authx.AccountX{Address: addr, MemoRequired: true})
+		require.Nil(t, app.marketKeeper.SetMarket(ctx, info))
+	})
+	commitBlock(app, func(ctx sdk.Context) {
+		acc := app.accountKeeper.GetAccount(ctx, addr)
+		require.Nil(t, acc.SetCoins(dex.NewCetCoins(900)))
+		app.accountKeeper.SetAccount(ctx, acc)
+		require.Nil(t, app.marketKeeper.RemoveMarket(ctx, info.GetSymbol()))
+	})
+
+	// added between the first two blocks
+	changes := collectStateDiff(t, db, 1, 2)
+	accxKey := "accx/" + "01" + hex.EncodeToString(addr)
+	require.Contains(t, changes, accxKey)
+	require.Equal(t, ChangeAdded, changes[accxKey].Type)
+	require.Nil(t, changes[accxKey].From)
+	var accx authx.AccountX
+	require.Nil(t, app.cdc.UnmarshalJSON(changes[accxKey].To, &accx))
+	require.Equal(t, addr, accx.Address)
+	require.True(t, accx.MemoRequired)
+	marketKey := "market/" + hex.EncodeToString(append([]byte{0x15}, info.GetSymbol()...))
+	require.Equal(t, ChangeAdded, changes[marketKey].Type)
+	var decoded market.MarketInfo
+	require.Nil(t, app.cdc.UnmarshalJSON(changes[marketKey].To, &decoded))
+	require.Equal(t, info, decoded)
+
+	// modified and removed by the third block
+	changes = collectStateDiff(t, db, 2, 3)
+	accKey := "acc/" + "01" + hex.EncodeToString(addr)
+	require.Equal(t, ChangeModified, changes[accKey].Type)
+	var fromAcc, toAcc auth.Account
+	require.Nil(t, app.cdc.UnmarshalJSON(changes[accKey].From, &fromAcc))
+	require.Nil(t, app.cdc.UnmarshalJSON(changes[accKey].To, &toAcc))
+	require.Equal(t, dex.NewCetCoins(1000), fromAcc.GetCoins())
+	require.Equal(t, dex.NewCetCoins(900), toAcc.GetCoins())
+	removed := changes[marketKey]
+	require.Equal(t, ChangeRemoved, removed.Type)
+	require.Nil(t, removed.To)
+	require.NotContains(t, changes, accxKey)
+
+	// the unchanged state and the unknown layouts
+	require.Empty(t, collectStateDiff(t, db, 3, 3))
+	var s string
+	require.Nil(t, json.Unmarshal(app.decodeStoreValue(market.StoreKey, []byte{0x99}, []byte{0xab}), &s))
+	require.Equal(t, "ab", s)
+}
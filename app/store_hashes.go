@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// FlagLogStoreHashes logs the commit hashes of the mounted stores after every
+// commit, so the logs of two nodes can be diffed to find the diverged store
+const FlagLogStoreHashes = "log-store-hashes"
+
+// StoreHash is the commit hash of a mounted store at the loaded height
+type StoreHash struct {
+	Name string
+	Hash []byte
+}
+
+// StoreHashes returns the commit hashes of the KV stores, in the order of
+// mountStores. The transient stores are not committed and have no hash.
+func (app *CetChainApp) StoreHashes() []StoreHash {
+	keys := app.kvStoreKeys()
+	hashes := make([]StoreHash, len(keys))
+	for i, key := range keys {
+		hashes[i] = StoreHash{
+			Name: key.Name(),
+			Hash: app.cms.GetCommitKVStore(key).LastCommitID().Hash,
+		}
+	}
+	return hashes
+}
+
+// logStoreHashes must be called after the block is committed
+func (app *CetChainApp) logStoreHashes() {
+	if !viper.GetBool(FlagLogStoreHashes) {
+		return
+	}
+	keyvals := []interface{}{"height", app.LastBlockHeight(), "app_hash", fmt.Sprintf("%X", app.LastCommitID().Hash)}
+	for _, h := range app.StoreHashes() {
+		keyvals = append(keyvals, h.Name, fmt.Sprintf("%X", h.Hash))
+	}
+	app.Logger().Info("store hashes", keyvals...)
+}
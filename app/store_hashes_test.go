@@ -0,0 +1,53 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	bam "github.com/cosmos/cosmos-sdk/baseapp"
+	"github.com/cosmos/cosmos-sdk/store"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestStoreHashes(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	db := dbm.NewMemDB()
+	app := NewCetChainApp(log.NewNopLogger(), db, nil, true, 0, bam.SetPruning(store.PruneNothing))
+	genState := NewDefaultGenesisState()
+	genState.AssetData.Tokens = append(genState.AssetData.Tokens, cetToken())
+	genState.StakingData.Params.BondDenom = dex.DefaultBondDenom
+	addGenesisAccounts(&genState, auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1000)})
+	app.InitChain(abci.RequestInitChain{ChainId: testChainID, AppStateBytes: app.cdc.MustMarshalJSON(genState)})
+	commitBlock(app, func(ctx sdk.Context) {})
+	first := app.StoreHashes()
+	commitBlock(app, func(ctx sdk.Context) {
+		app.accountXKeeper.SetAccountX(ctx, authx.AccountX{Address: addr, MemoRequired: true})
+	})
+	second := app.StoreHashes()
+
+	require.Equal(t, 16, len(second))
+	for i, h := range second {
+		require.Equal(t, app.kvStoreKeys()[i].Name(), h.Name)
+		if h.Name == authx.StoreKey {
+			require.NotEqual(t, first[i].Hash, h.Hash)
+		} else if h.Name == asset.StoreKey {
+			require.Equal(t, first[i].Hash, h.Hash)
+		}
+	}
+
+	// the hashes of a loaded height
+	loaded := NewCetChainApp(log.NewNopLogger(), db, nil, false, 0)
+	require.Nil(t, loaded.LoadHeight(1))
+	require.Equal(t, first, loaded.StoreHashes())
+}
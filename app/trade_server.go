@@ -0,0 +1,128 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gorilla/mux"
+	toml "github.com/pelletier/go-toml"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+
+	tserver "github.com/coinexchain/trade-server/server"
+)
+
+// the value types expected by trade-server, it panics on a mismatched type
+var tradeServerConfTypes = map[string]string{
+	"port":            "int64",
+	"interval":        "int64",
+	"keepRecent":      "int64",
+	"monitorinterval": "int64",
+	"initChainHeight": "int64",
+	"upgrade-height":  "int64",
+	"data-dir":        "string",
+	"cert-dir":        "string",
+	"log-dir":         "string",
+	"log-level":       "string",
+	"log-format":      "string",
+	"dir":             "string",
+	"file-prefix":     "string",
+	"chain-id":        "string",
+	"kafka-addrs":     "string",
+	"lcd":             "string",
+	"lcdv0":           "string",
+	"backup-file":     "string",
+	"https-toggle":    "bool",
+	"dir-mode":        "bool",
+	"proxy":           "bool",
+	"use-rocksdb":     "bool",
+	"backup-toggle":   "bool",
+}
+
+var tradeServerLogLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+// CheckTradeServerConfig validates the content of trade-server.toml
+func CheckTradeServerConfig(conf *toml.Tree) error {
+	for key, expected := range tradeServerConfTypes {
+		if !conf.Has(key) {
+			continue
+		}
+		if actual := fmt.Sprintf("%T", conf.Get(key)); actual != expected {
+			return fmt.Errorf("trade-server config %s should be %s, got %s", key, expected, actual)
+		}
+	}
+
+	if port := conf.GetDefault("port", int64(8000)).(int64); port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid trade-server port: %d", port)
+	}
+	if level := conf.GetDefault("log-level", "info").(string); !tradeServerLogLevels[level] {
+		return fmt.Errorf("invalid trade-server log-level: %s", level)
+	}
+	if conf.GetDefault("https-toggle", false).(bool) {
+		certDir := conf.GetDefault("cert-dir", "cert").(string)
+		if info, err := os.Stat(certDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("trade-server cert-dir %s does not exist", certDir)
+		}
+	}
+	if conf.GetDefault("dir-mode", false).(bool) {
+		if len(conf.GetDefault("dir", "").(string)) == 0 {
+			return errors.New("trade-server dir is required in dir-mode")
+		}
+	} else if len(conf.GetDefault("kafka-addrs", "").(string)) == 0 {
+		return errors.New("trade-server kafka-addrs is required when dir-mode is off")
+	}
+	dataDir := conf.GetDefault("data-dir", "data").(string)
+	if info, err := os.Stat(dataDir); err == nil && !info.IsDir() {
+		return fmt.Errorf("trade-server data-dir %s is not a directory", dataDir)
+	}
+	return nil
+}
+
+// IsTradeServerEnabled returns true if a prune-prefixed broker is configured
+func IsTradeServerEnabled() bool {
+	return isOpenTs()
+}
+
+// ReadTradeServerConfig loads and validates trade-server.toml of the node
+func ReadTradeServerConfig() (*toml.Tree, error) {
+	conf, err := initConf()
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckTradeServerConfig(conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// StartTradeServer starts the embedded trade-server if it is enabled, it
+// must be called after the app is loaded. The REST routes of its embedded
+// LCD use nodeClient, the in-process client of the node, for broadcasting.
+func (app *CetChainApp) StartTradeServer(nodeClient rpcclient.Client) error {
+	if !isOpenTs() || app.ts != nil {
+		return nil
+	}
+	conf, err := ReadTradeServerConfig()
+	if err != nil {
+		return fmt.Errorf("init trade-server conf failed, err : %s", err.Error())
+	}
+	registerRoutes := func(router *mux.Router) {
+		app.CreateContextAndRegisterRoutes(nodeClient, router)
+	}
+	if app.ts = tserver.NewTradeServer(conf, registerRoutes); app.ts == nil {
+		return errors.New("init trade-server failed")
+	}
+	app.ts.Start(conf)
+	app.Logger().Info("trade-server started")
+	return nil
+}
+
+// StopTradeServer stops the embedded trade-server and flushes its data
+func (app *CetChainApp) StopTradeServer() {
+	if app.ts == nil {
+		return
+	}
+	app.ts.Stop()
+	app.ts = nil
+	app.Logger().Info("trade-server stopped")
+}
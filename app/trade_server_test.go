@@ -0,0 +1,42 @@
+package app
+
+import (
+	"testing"
+
+	toml "github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTradeServerConfig(t *testing.T) {
+	conf, err := toml.LoadFile("../trade-server.toml.default")
+	require.Nil(t, err)
+	require.Nil(t, CheckTradeServerConfig(conf))
+
+	conf.Set("port", "9000")
+	require.Error(t, CheckTradeServerConfig(conf))
+	conf.Set("port", int64(70000))
+	require.Error(t, CheckTradeServerConfig(conf))
+	conf.Set("port", int64(9000))
+
+	conf.Set("log-level", "verbose")
+	require.Error(t, CheckTradeServerConfig(conf))
+	conf.Set("log-level", "debug")
+
+	conf.Set("dir", "")
+	require.Error(t, CheckTradeServerConfig(conf))
+	conf.Set("dir-mode", false)
+	require.Error(t, CheckTradeServerConfig(conf))
+	conf.Set("kafka-addrs", "localhost:9092")
+	require.Nil(t, CheckTradeServerConfig(conf))
+
+	conf.Set("https-toggle", true)
+	conf.Set("cert-dir", "/not/exist/cert")
+	require.Error(t, CheckTradeServerConfig(conf))
+}
+
+func TestStartTradeServerDisabled(t *testing.T) {
+	app := &CetChainApp{}
+	require.Nil(t, app.StartTradeServer(nil))
+	require.Nil(t, app.ts)
+	app.StopTradeServer()
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/tendermint/tendermint/crypto/tmhash"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// the number of recently decoded txs kept in the cache, it should be larger
+// than the number of txs in a block
+const txDecodeCacheSize = 8192
+
+type decodedTx struct {
+	tx  sdk.Tx
+	err sdk.Error
+}
+
+// txDecodeCache remembers the recently decoded txs by their hashes. Its Decode
+// is used as the TxDecoder of BaseApp, the plugin, the unconfirmed tx limiter
+// and the notifications, so a tx is decoded only once in CheckTx and once in
+// DeliverTx, instead of once by each of them. The decoded txs are shared and
+// must not be modified.
+type txDecodeCache struct {
+	mtx     sync.Mutex
+	decoder sdk.TxDecoder
+	entries map[string]decodedTx
+	keys    []string // a ring of the cached keys, the oldest is evicted first
+	next    int
+}
+
+func newTxDecodeCache(decoder sdk.TxDecoder, size int) *txDecodeCache {
+	return &txDecodeCache{
+		decoder: decoder,
+		entries: make(map[string]decodedTx, size),
+		keys:    make([]string, size),
+	}
+}
+
+// Decode implements sdk.TxDecoder
+func (c *txDecodeCache) Decode(txBytes []byte) (sdk.Tx, sdk.Error) {
+	key := string(tmhash.Sum(txBytes))
+
+	c.mtx.Lock()
+	entry, ok := c.entries[key]
+	c.mtx.Unlock()
+	if ok {
+		return entry.tx, entry.err
+	}
+
+	tx, err := c.decoder(txBytes)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		delete(c.entries, c.keys[c.next])
+		c.keys[c.next] = key
+		c.next = (c.next + 1) % len(c.keys)
+		c.entries[key] = decodedTx{tx: tx, err: err}
+	}
+	return tx, err
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func newTestTxBytes(t testing.TB, encoder sdk.TxEncoder, seq uint64) []byte {
+	key, _, fromAddr := testutil.KeyPubAddr()
+	_, _, toAddr := testutil.KeyPubAddr()
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1000000000), 0)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, seq, key).Build()
+	txBytes, err := encoder(tx)
+	require.Nil(t, err)
+	return txBytes
+}
+
+func TestTxDecodeCache(t *testing.T) {
+	cdc := MakeCodec()
+	decodeCount := 0
+	amino := auth.DefaultTxDecoder(cdc)
+	decoder := func(txBytes []byte) (sdk.Tx, sdk.Error) {
+		decodeCount++
+		return amino(txBytes)
+	}
+	cache := newTxDecodeCache(decoder, 2)
+
+	tx1 := newTestTxBytes(t, auth.DefaultTxEncoder(cdc), 1)
+	tx2 := newTestTxBytes(t, auth.DefaultTxEncoder(cdc), 2)
+	tx3 := newTestTxBytes(t, auth.DefaultTxEncoder(cdc), 3)
+
+	decoded, err := cache.Decode(tx1)
+	require.Nil(t, err)
+	_, ok := decoded.(auth.StdTx)
+	require.True(t, ok)
+	decodedAgain, err := cache.Decode(tx1)
+	require.Nil(t, err)
+	require.Equal(t, decoded, decodedAgain)
+	require.Equal(t, 1, decodeCount)
+
+	// the oldest tx is evicted
+	_, _ = cache.Decode(tx2)
+	_, _ = cache.Decode(tx3)
+	require.Equal(t, 3, decodeCount)
+	_, _ = cache.Decode(tx3)
+	require.Equal(t, 3, decodeCount)
+	_, _ = cache.Decode(tx1)
+	require.Equal(t, 4, decodeCount)
+
+	// the errors are cached too
+	_, err = cache.Decode([]byte("bad tx"))
+	require.NotNil(t, err)
+	_, err = cache.Decode([]byte("bad tx"))
+	require.NotNil(t, err)
+	require.Equal(t, 5, decodeCount)
+}
+
+// A tx is decoded by the plugin, the CetChainApp and the BaseApp in CheckTx,
+// and by the CetChainApp and the BaseApp in DeliverTx.
+const decodesPerTx = 5
+
+func BenchmarkTxDecoderAmino(b *testing.B) {
+	cdc := MakeCodec()
+	decoder := auth.DefaultTxDecoder(cdc)
+	txBytes := newTestTxBytes(b, auth.DefaultTxEncoder(cdc), 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < decodesPerTx; j++ {
+			_, _ = decoder(txBytes)
+		}
+	}
+}
+
+func BenchmarkTxDecoderCached(b *testing.B) {
+	cdc := MakeCodec()
+	txs := make([][]byte, 1000)
+	for i := range txs {
+		txs[i] = newTestTxBytes(b, auth.DefaultTxEncoder(cdc), uint64(i))
+	}
+	decoder := newTxDecodeCache(auth.DefaultTxDecoder(cdc), txDecodeCacheSize).Decode
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// a different tx each time, so the first decode of every tx is a miss
+		if i%len(txs) == 0 {
+			decoder = newTxDecodeCache(auth.DefaultTxDecoder(cdc), txDecodeCacheSize).Decode
+		}
+		txBytes := txs[i%len(txs)]
+		for j := 0; j < decodesPerTx; j++ {
+			_, _ = decoder(txBytes)
+		}
+	}
+}
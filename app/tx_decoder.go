@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+	cmn "github.com/tendermint/tendermint/libs/common"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	dexcodec "github.com/coinexchain/dex/codec"
+)
+
+// FlagTxDecoder selects the decoder of the txs. Both decoders read the txs
+// encoded by amino and give the same results, the codon one decodes the StdTx
+// without reflection and falls back to amino for the other inputs.
+const FlagTxDecoder = "tx-decoder"
+
+const (
+	TxDecoderAmino = "amino"
+	TxDecoderCodon = "codon"
+)
+
+func newTxDecoder(cdc *codec.Codec, name string) (sdk.TxDecoder, error) {
+	switch name {
+	case "", TxDecoderAmino:
+		return auth.DefaultTxDecoder(cdc), nil
+	case TxDecoderCodon:
+		return dexcodec.NewTxDecoder(auth.DefaultTxDecoder(cdc)), nil
+	default:
+		return nil, fmt.Errorf("unknown tx decoder: %s", name)
+	}
+}
+
+func newTxDecoderFromConfig(cdc *codec.Codec) sdk.TxDecoder {
+	txDecoder, err := newTxDecoder(cdc, viper.GetString(FlagTxDecoder))
+	if err != nil {
+		cmn.Exit(err.Error())
+	}
+	return txDecoder
+}
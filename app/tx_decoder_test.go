@@ -0,0 +1,109 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+
+	dexcodec "github.com/coinexchain/dex/codec"
+)
+
+// requireSameDecoding requires the decoders to return the same tx or error
+func requireSameDecoding(t *testing.T, expected, actual sdk.TxDecoder, txBytes []byte) {
+	expectedTx, expectedErr := expected(txBytes)
+	tx, err := actual(txBytes)
+	require.Equal(t, expectedTx, tx)
+	if expectedErr == nil {
+		require.Nil(t, err)
+	} else {
+		require.NotNil(t, err)
+		require.Equal(t, expectedErr.Error(), err.Error())
+	}
+}
+
+func TestCodonTxDecoder(t *testing.T) {
+	cdc := MakeCodec()
+	key, _, fromAddr := testutil.KeyPubAddr()
+	_, _, toAddr := testutil.KeyPubAddr()
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1000000000), 0)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	tx.Memo = "memo"
+	txBytes, err := auth.DefaultTxEncoder(cdc)(tx)
+	require.Nil(t, err)
+
+	decoder, err := newTxDecoder(cdc, TxDecoderCodon)
+	require.Nil(t, err)
+	decoded, sdkErr := decoder(txBytes)
+	require.Nil(t, sdkErr)
+	require.Equal(t, tx, decoded)
+
+	aminoDecoder, err := newTxDecoder(cdc, TxDecoderAmino)
+	require.Nil(t, err)
+	requireSameDecoding(t, aminoDecoder, decoder, nil)
+	requireSameDecoding(t, aminoDecoder, decoder, []byte{0})
+	for i := 0; i < len(txBytes); i++ {
+		requireSameDecoding(t, aminoDecoder, decoder, txBytes[:i])
+	}
+
+	_, err = newTxDecoder(cdc, "gob")
+	require.NotNil(t, err)
+}
+
+// TestCodonTxDecoderDifferential decodes the random txs, whose msgs are of all
+// the types in codec.GetSupportList(), and their malformed variants with both
+// decoders
+func TestCodonTxDecoderDifferential(t *testing.T) {
+	cdc := MakeCodec()
+	aminoDecoder := auth.DefaultTxDecoder(cdc)
+	rejectAll := func([]byte) (sdk.Tx, sdk.Error) {
+		return nil, sdk.ErrTxDecode("rejected")
+	}
+	// without fallback, to check the txs are decoded by codon
+	codonOnly := dexcodec.NewTxDecoder(rejectAll)
+	decoder := dexcodec.NewTxDecoder(aminoDecoder)
+
+	r := dexcodec.NewRandSrc(0)
+	msgTypes := make(map[reflect.Type]bool)
+	for i := 0; i < 300; i++ {
+		stdTx := dexcodec.RandStdTx(r)
+		txBytes, err := cdc.MarshalBinaryLengthPrefixed(stdTx)
+		if err != nil {
+			// e.g. a time after year 9999
+			continue
+		}
+		expected, sdkErr := aminoDecoder(txBytes)
+		require.Nil(t, sdkErr)
+		tx, sdkErr := codonOnly(txBytes)
+		require.Nil(t, sdkErr)
+		require.Equal(t, expected, tx)
+		for _, msg := range stdTx.Msgs {
+			msgTypes[reflect.TypeOf(msg)] = true
+		}
+
+		for j := 0; j < len(txBytes); j += 1 + j/8 {
+			flipped := append([]byte(nil), txBytes...)
+			flipped[j] ^= byte(r.GetUint8()) | 1
+			requireSameDecoding(t, aminoDecoder, decoder, flipped)
+			requireSameDecoding(t, aminoDecoder, decoder, txBytes[:j])
+		}
+	}
+
+	registered, err := dexcodec.GetRegisteredTypes(MakeCodec())
+	require.Nil(t, err)
+	msgType := reflect.TypeOf((*sdk.Msg)(nil)).Elem()
+	for _, rt := range registered {
+		vt := reflect.TypeOf(rt.Value)
+		if reflect.PtrTo(vt).Implements(msgType) {
+			require.True(t, msgTypes[vt] || msgTypes[reflect.PtrTo(vt)], rt.Name)
+		}
+	}
+}
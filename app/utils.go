@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -12,7 +13,6 @@ import (
 
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/client/context"
-	"github.com/cosmos/cosmos-sdk/client/flags"
 	authrest "github.com/cosmos/cosmos-sdk/x/auth/client/rest"
 
 	"github.com/coinexchain/cet-sdk/msgqueue"
@@ -30,15 +30,20 @@ func initConf() (*toml.Tree, error) {
 	}
 	filePath := conf.RootDir + "/config/trade-server.toml"
 	info, err := os.Stat(filePath)
-	if err != nil || info.IsDir() {
+	if err != nil {
 		return nil, err
 	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", filePath)
+	}
 	config, err := toml.LoadFile(filePath)
 	if err != nil {
 		return config, err
 	}
-	path := strings.Split(getPreFixBks(msgqueue.CfgPrefixPrune), msgqueue.CfgPrefixPrune)[1]
-	config.Set(TSDirCfg, path)
+	if isOpenTs() {
+		path := strings.Split(getPreFixBks(msgqueue.CfgPrefixPrune), msgqueue.CfgPrefixPrune)[1]
+		config.Set(TSDirCfg, path)
+	}
 	return config, err
 }
 
@@ -57,27 +62,21 @@ func getPreFixBks(prefix string) string {
 	return ""
 }
 
-func CreateContextAndRegisterRoutes(router *mux.Router) {
-	var ctx = newCLIContextForEmbeddedLDC()
+// CreateContextAndRegisterRoutes registers the REST routes of the embedded LCD,
+// which are served by the running app through the in-process client
+func (app *CetChainApp) CreateContextAndRegisterRoutes(nodeClient rpcclient.Client, router *mux.Router) {
+	var ctx = app.newCLIContextForEmbeddedLDC(nodeClient)
 	client.RegisterRoutes(ctx, router)
 	authrest.RegisterTxRoutes(ctx, router)
 	ModuleBasics.RegisterRESTRoutes(ctx, router)
 }
 
 // see cosmos-sdk/client/context/context.go#NewCLIContextWithFrom()
-func newCLIContextForEmbeddedLDC() context.CLIContext {
-	var cdc = MakeCodec()
-	var nodeURI = viper.GetString(flags.FlagNode)
-	if nodeURI == "" {
-		nodeURI = "tcp://localhost:26657"
-	}
-	var rpc = rpcclient.NewHTTP(nodeURI, "/websocket")
-
+func (app *CetChainApp) newCLIContextForEmbeddedLDC(nodeClient rpcclient.Client) context.CLIContext {
 	// fill members of ctx
 	return context.CLIContext{
-		Codec:     cdc,
-		Client:    rpc,
-		NodeURI:   nodeURI,
+		Codec:     app.cdc,
+		Client:    newLocalRPCClient(app, nodeClient),
 		TrustNode: true,
 
 		// default values is enough?
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// ValidateGenesisStream validates the genesis doc read from r as
+// `cetd validate-genesis` does, but decodes the large arrays of the streaming
+// export element by element, so a genesis written by StreamGenesis is never
+// held in memory as a whole. Only the keys of the elements are kept, to find
+// the duplicates.
+//
+// The modules validate their genesis without the streamed arrays, whose
+// elements are checked here as the modules would do:
+//   - an account of genaccounts by its ValidateGenesis, and its address is unique
+//   - an accountX of authx has a unique and non-empty address
+//   - an order of market has a unique ID, and its trading pair is in the
+//     market_infos, which the ValidateGenesis of market does not check
+func ValidateGenesisStream(r io.Reader) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	fields := make(map[string]json.RawMessage)
+	var genState map[string]json.RawMessage
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+		if key == "app_state" {
+			if genState, err = readAppState(dec); err != nil {
+				return err
+			}
+			continue
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Errorf("invalid %s: %v", key, err)
+		}
+		fields[key] = v
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+
+	bz, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if _, err := tmtypes.GenesisDocFromJSON(bz); err != nil {
+		return err
+	}
+	if genState == nil {
+		return fmt.Errorf("app_state not found in the genesis")
+	}
+	return ModuleBasics.ValidateGenesis(genState)
+}
+
+// readAppState validates the streamed arrays in the app state and returns the
+// genesis of the modules without them, to be validated by the modules
+func readAppState(dec *json.Decoder) (map[string]json.RawMessage, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	genState := make(map[string]json.RawMessage)
+	for dec.More() {
+		name, err := readKey(dec)
+		if err != nil {
+			return nil, err
+		}
+		var v json.RawMessage
+		if array, ok := streamedArrays[name]; ok {
+			v, err = array.read(dec)
+		} else {
+			err = dec.Decode(&v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid genesis of %s: %v", name, err)
+		}
+		genState[name] = v
+	}
+	return genState, expectDelim(dec, '}')
+}
+
+// read checks the elements of the array one by one and returns the genesis
+// of the module without the array
+func (array streamedArray) read(dec *json.Decoder) (json.RawMessage, error) {
+	if array.field == "" {
+		if _, err := array.readElements(dec); err != nil {
+			return nil, err
+		}
+		return json.RawMessage(array.empty), nil
+	}
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	head := make(map[string]json.RawMessage)
+	var refs map[string]struct{}
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return nil, err
+		}
+		if key == array.field {
+			refs, err = array.readElements(dec)
+		} else {
+			var v json.RawMessage
+			err = dec.Decode(&v)
+			head[key] = v
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	if array.checkRefs != nil {
+		if err := array.checkRefs(array.cdc, head, refs); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(head)
+}
+
+// readElements checks the elements and returns the refs of them, which are
+// far fewer than the elements, e.g. the trading pairs of the orders
+func (array streamedArray) readElements(dec *json.Decoder) (map[string]struct{}, error) {
+	refs := make(map[string]struct{})
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return refs, nil
+	}
+	if tok != json.Delim('[') {
+		return nil, fmt.Errorf("expected an array, got %v", tok)
+	}
+	keys := make(map[string]struct{})
+	for i := 0; dec.More(); i++ {
+		var elem json.RawMessage
+		if err := dec.Decode(&elem); err != nil {
+			return nil, err
+		}
+		key, ref, err := array.check(array.cdc, elem)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+		if _, ok := keys[key]; ok {
+			return nil, fmt.Errorf("duplicate element %s", key)
+		}
+		keys[key] = struct{}{}
+		if ref != "" {
+			refs[ref] = struct{}{}
+		}
+	}
+	return refs, expectDelim(dec, ']')
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a key, got %v", tok)
+	}
+	return key, nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, got %v", delim, tok)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+)
+
+func debugCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Tools for debugging the application state",
+	}
+	cmd.AddCommand(storeHashesCmd(ctx))
+	return cmd
+}
+
+func storeHashesCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "store-hashes",
+		Short: "Print the commit hash of each mounted store",
+		Long: `Load the application state at a height and print the commit hash of each
+mounted KV store, followed by the app hash. When two nodes disagree on the app
+hash, diffing their outputs shows which stores diverged. Start the node with
+--log-store-hashes to log the hashes on every commit instead.
+
+Example:
+$ cetd debug store-hashes --height 100
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(flags.FlagHome))
+
+			gApp, db, err := loadAppAtHeight(ctx, viper.GetInt64(flagHeight))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			fmt.Printf("height %d\n", gApp.LastBlockHeight())
+			for _, h := range gApp.StoreHashes() {
+				fmt.Printf("%-12s %X\n", h.Name, h.Hash)
+			}
+			fmt.Printf("%-12s %X\n", "app_hash", gApp.LastCommitID().Hash)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64(flagHeight, -1, "The height to print the hashes of (-1 means latest height)")
+	return cmd
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tendermint/tendermint/crypto"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	flagSanitize       = "sanitize"
+	flagSanitizeSeed   = "sanitize-seed"
+	flagTestValidators = "test-validators"
+	flagAddressMap     = "address-map"
+)
+
+// overrideExportCmd adds the --sanitize mode to the `export` command added by
+// server.AddCommands, which exports the state for a private test network
+func overrideExportCmd(ctx *server.Context, cdc *codec.Codec, rootCmd *cobra.Command) {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() != "export" {
+			continue
+		}
+		cmd.Long = `Export state to JSON.
+
+With --sanitize, the state is exported for a private test network as a zero height
+genesis. Every address, except the ones of the module accounts, is replaced by the
+address of the secp256k1 key generated from the seed followed by the original address,
+and the bonded validators with the most power are taken over by the test validators,
+one for each, while the others are jailed. The balances, orders, tokens and markets
+are kept, and the replaced addresses are written to the address map file.
+
+Example:
+$ cetd export --sanitize --sanitize-seed testnet \
+	--test-validators coinexvalconspub1...,coinexvalconspub1... > genesis.json
+`
+		export := cmd.RunE
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if !viper.GetBool(flagSanitize) {
+				return export(cmd, args)
+			}
+			return exportSanitized(ctx, cdc)
+		}
+		cmd.Flags().Bool(flagSanitize, false, "Replace the addresses and the validators for a private test network")
+		cmd.Flags().String(flagSanitizeSeed, "", "The seed of the test keys replacing the addresses")
+		cmd.Flags().StringSlice(flagTestValidators, []string{}, "The consensus pubkeys of the test validators")
+		cmd.Flags().String(flagAddressMap, "address_map.json", "The file to write the replaced addresses to")
+	}
+}
+
+func exportSanitized(ctx *server.Context, cdc *codec.Codec) error {
+	config := ctx.Config
+	config.SetRoot(viper.GetString(flags.FlagHome))
+
+	seed := viper.GetString(flagSanitizeSeed)
+	if seed == "" {
+		return fmt.Errorf("--%s is required by --%s", flagSanitizeSeed, flagSanitize)
+	}
+	var testValidators []crypto.PubKey
+	for _, s := range viper.GetStringSlice(flagTestValidators) {
+		pk, err := sdk.GetConsPubKeyBech32(s)
+		if err != nil {
+			return fmt.Errorf("invalid test validator %s: %v", s, err)
+		}
+		testValidators = append(testValidators, pk)
+	}
+
+	doc, err := tmtypes.GenesisDocFromFile(config.GenesisFile())
+	if err != nil {
+		return err
+	}
+	gApp, db, err := loadAppAtHeight(ctx, viper.GetInt64(flagHeight))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	appState, validators, mapping, err := gApp.ExportSanitizedAppStateAndValidators(seed, testValidators)
+	if err != nil {
+		return fmt.Errorf("error exporting state: %v", err)
+	}
+
+	bz, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(viper.GetString(flagAddressMap), bz, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d addresses replaced, written to %s\n", len(mapping.Accounts), viper.GetString(flagAddressMap))
+
+	doc.AppState = appState
+	doc.Validators = validators
+	encoded, err := codec.MarshalJSONIndent(cdc, doc)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(sdk.MustSortJSON(encoded)))
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagHeight        = "height"
+	flagForZeroHeight = "for-zero-height"
+	flagJailWhitelist = "jail-whitelist"
+)
+
+func exportStreamCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-stream [output-file]",
+		Short: "Export state to a genesis file, writing the large arrays element by element",
+		Long: `Export state to a genesis file as 'cetd export' does, but write the state of
+the modules one by one, and the accounts and the orders element by element, so
+the state is never held in memory as a whole. The keys are sorted as the ones of
+'cetd export', and the output can be checked by 'cetd validate-genesis-stream'.
+
+Example:
+$ cetd export-stream --height 100 /tmp/genesis.json
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(flags.FlagHome))
+
+			doc, err := tmtypes.GenesisDocFromFile(config.GenesisFile())
+			if err != nil {
+				return err
+			}
+			gApp, db, err := loadAppAtHeight(ctx, viper.GetInt64(flagHeight))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			out, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+			err = gApp.StreamGenesis(out, doc, viper.GetBool(flagForZeroHeight), viper.GetStringSlice(flagJailWhitelist))
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("error exporting state: %v", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64(flagHeight, -1, "Export state from a particular height (-1 means latest height)")
+	cmd.Flags().Bool(flagForZeroHeight, false, "Export state to start at height zero (perform preproccessing)")
+	cmd.Flags().StringSlice(flagJailWhitelist, []string{}, "List of validators to not jail state export")
+	return cmd
+}
+
+func validateGenesisStreamCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate-genesis-stream [genesis-file]",
+		Short: "Validate a genesis file without loading it in memory as a whole",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := app.ValidateGenesisStream(f); err != nil {
+				return fmt.Errorf("error validating genesis file %s: %v", args[0], err)
+			}
+			fmt.Printf("File at %s is a valid genesis file\n", args[0])
+			return nil
+		},
+	}
+}
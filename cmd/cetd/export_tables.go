@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const flagOut = "out"
+
+func exportTablesCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-tables",
+		Short: "Export the balances, tokens, orders, bancors and delegations as CSV files",
+		Long: `Export the state as flat tables, one CSV file for each entity in the output
+directory: accounts, accountx_locked_coins, tokens, orders, markets, bancors,
+delegations and unbonding_entries. The columns of a table are fixed, except the
+balances of the accounts, one column for each denomination in sorted order.
+
+Example:
+$ cetd export-tables --height 100 --out /tmp/tables
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(flags.FlagHome))
+
+			gApp, db, err := loadAppAtHeight(ctx, viper.GetInt64(flagHeight))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			genState := gApp.ExportGenesisState(gApp.NewContext(true, abci.Header{Height: gApp.LastBlockHeight()}))
+
+			out := viper.GetString(flagOut)
+			if err := os.MkdirAll(out, 0755); err != nil {
+				return err
+			}
+			for _, table := range app.GenesisTables(genState) {
+				if err := writeCSV(filepath.Join(out, table.Name+".csv"), table); err != nil {
+					return fmt.Errorf("error writing table %s: %v", table.Name, err)
+				}
+				fmt.Fprintf(os.Stderr, "%d rows written to %s.csv\n", len(table.Rows), table.Name)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64(flagHeight, -1, "Export state from a particular height (-1 means latest height)")
+	cmd.Flags().String(flagOut, ".", "The directory to write the CSV files to")
+	return cmd
+}
+
+func writeCSV(path string, table app.GenesisTable) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write(table.Header)
+	_ = w.WriteAll(table.Rows)
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
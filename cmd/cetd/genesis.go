@@ -0,0 +1,497 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+	"github.com/cosmos/cosmos-sdk/x/genutil"
+	"github.com/cosmos/cosmos-sdk/x/params"
+	"github.com/cosmos/cosmos-sdk/x/slashing"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/cet-sdk/modules/alias"
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/modules/incentive"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/modules/stakingx"
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagPricePrecision     = "price-precision"
+	flagOrderPrecision     = "order-precision"
+	flagInitPrice          = "init-price"
+	flagMaxPrice           = "max-price"
+	flagMaxSupply          = "max-supply"
+	flagMaxMoney           = "max-money"
+	flagStockPrecision     = "stock-precision"
+	flagEarliestCancelTime = "earliest-cancel-time"
+	flagAsDefault          = "as-default"
+)
+
+const (
+	// the same as the ones of market and bancorlite, which are internal
+	maxOrderPrecision = 8
+	maxStockPrecision = 8
+	bancorARSamples   = 1000
+)
+
+// genesisEdit edits the typed genesis state, which is validated again
+// before the genesis file is written
+type genesisEdit func(genState *app.GenesisState) error
+
+func genesisCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "genesis",
+		Short: "Edit the params, the markets, the bancors and the aliases in genesis.json",
+		Long: `Edit genesis.json of the node in place. After every edit, the genesis of all the
+modules is validated again, and genesis.json is only written when it is valid.`,
+	}
+	cmd.AddCommand(
+		setParamCmd(ctx, cdc),
+		addMarketCmd(ctx, cdc),
+		addBancorCmd(ctx, cdc),
+		setAliasCmd(ctx, cdc),
+	)
+	return cmd
+}
+
+func setParamCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-param [module] [key] [value]",
+		Short: "Set a param of a module in genesis.json",
+		Long: `Set a param of a module in genesis.json. The key is the one of the ParamSetPairs
+of the module, and the value is decoded as the param is in the params store.
+
+Example:
+$ cetd genesis set-param market MarketFeeRate 10
+$ cetd genesis set-param authx MinGasPriceLimit 0.5
+$ cetd genesis set-param staking UnbondingTime 1814400000000000
+`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return editGenesisFile(ctx, cdc, func(genState *app.GenesisState) error {
+				return setParam(cdc, genState, args[0], args[1], args[2])
+			})
+		},
+	}
+}
+
+func addMarketCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add-market [stock] [money]",
+		Short: "Add a trading pair to genesis.json",
+		Long: `Add a trading pair to genesis.json. The stock and the money must be tokens in
+genesis.json, and the trading pair is created by the owner of the stock.
+
+Example:
+$ cetd genesis add-market abc cet --price-precision 8
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			pricePrecision := byte(viper.GetUint(flagPricePrecision))
+			orderPrecision := byte(viper.GetUint(flagOrderPrecision))
+			return editGenesisFile(ctx, cdc, func(genState *app.GenesisState) error {
+				return addMarket(genState, args[0], args[1], pricePrecision, orderPrecision)
+			})
+		},
+	}
+	cmd.Flags().Uint(flagPricePrecision, 8, "The price precision of the trading pair")
+	cmd.Flags().Uint(flagOrderPrecision, 0, "The order precision of the trading pair")
+	return cmd
+}
+
+func addBancorCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add-bancor [stock] [money]",
+		Short: "Add a bancor to genesis.json",
+		Long: `Add a bancor to genesis.json. The bancor is owned by the owner of the stock,
+whose account in genesis.json must hold the max supply, which is frozen as
+bancor-init does.
+
+Example:
+$ cetd genesis add-bancor abc cet --max-supply 10000000000 --init-price 1 --max-price 10
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			maxSupply, ok := sdk.NewIntFromString(viper.GetString(flagMaxSupply))
+			if !ok {
+				return fmt.Errorf("invalid %s: %s", flagMaxSupply, viper.GetString(flagMaxSupply))
+			}
+			maxMoney, ok := sdk.NewIntFromString(viper.GetString(flagMaxMoney))
+			if !ok {
+				return fmt.Errorf("invalid %s: %s", flagMaxMoney, viper.GetString(flagMaxMoney))
+			}
+			msg := bancorlite.MsgBancorInit{
+				Stock:              args[0],
+				Money:              args[1],
+				InitPrice:          viper.GetString(flagInitPrice),
+				MaxSupply:          maxSupply,
+				MaxPrice:           viper.GetString(flagMaxPrice),
+				MaxMoney:           maxMoney,
+				StockPrecision:     byte(viper.GetUint(flagStockPrecision)),
+				EarliestCancelTime: viper.GetInt64(flagEarliestCancelTime),
+			}
+			return editGenesisFile(ctx, cdc, func(genState *app.GenesisState) error {
+				return addBancor(genState, msg)
+			})
+		},
+	}
+	cmd.Flags().String(flagMaxSupply, "", "The max supply of the stock in the bancor")
+	cmd.Flags().String(flagInitPrice, "0", "The initial price of the stock")
+	cmd.Flags().String(flagMaxPrice, "", "The max price of the stock")
+	cmd.Flags().String(flagMaxMoney, "0", "The max money in the bancor, 0 for a linear price curve")
+	cmd.Flags().Uint8(flagStockPrecision, 0, "The precision of the stock traded with the bancor, at most 8")
+	cmd.Flags().Int64(flagEarliestCancelTime, 0, "The earliest unix time at which the bancor can be cancelled")
+	_ = cmd.MarkFlagRequired(flagMaxSupply)
+	_ = cmd.MarkFlagRequired(flagMaxPrice)
+	return cmd
+}
+
+func setAliasCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-alias [alias] [address]",
+		Short: "Set an alias of an address in genesis.json",
+		Long: `Set an alias of an address in genesis.json. An alias already set for the address
+is updated, while an alias of another address can not be taken.
+
+Example:
+$ cetd genesis set-alias super_super_boy coinex1... --as-default
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			addr, err := sdk.AccAddressFromBech32(args[1])
+			if err != nil {
+				return err
+			}
+			asDefault := viper.GetBool(flagAsDefault)
+			return editGenesisFile(ctx, cdc, func(genState *app.GenesisState) error {
+				return setAlias(cdc, genState, args[0], addr, asDefault)
+			})
+		},
+	}
+	cmd.Flags().Bool(flagAsDefault, false, "Make the alias the default one of the address")
+	return cmd
+}
+
+// editGenesisFile applies the edit to the genesis file of the node. Only the
+// modules changed by the edit are written back, so the modules unknown to
+// app.GenesisState are kept as they are.
+func editGenesisFile(ctx *server.Context, cdc *codec.Codec, edit genesisEdit) error {
+	config := ctx.Config
+	config.SetRoot(viper.GetString(flags.FlagHome))
+	genFile := config.GenesisFile()
+
+	appState, genDoc, err := genutil.GenesisStateFromGenFile(cdc, genFile)
+	if err != nil {
+		return err
+	}
+	if err = editAppState(cdc, appState, edit); err != nil {
+		return err
+	}
+	genDoc.AppState, err = cdc.MarshalJSON(appState)
+	if err != nil {
+		return err
+	}
+	return genutil.ExportGenesisFile(genDoc, genFile)
+}
+
+func editAppState(cdc *codec.Codec, appState map[string]json.RawMessage, edit genesisEdit) error {
+	genState := app.FromMap(cdc, appState)
+	before := genesisModulesOf(cdc, genState)
+	if err := edit(&genState); err != nil {
+		return err
+	}
+	for name, bz := range genesisModulesOf(cdc, genState) {
+		if !bytes.Equal(bz, before[name]) {
+			appState[name] = bz
+		}
+	}
+	if err := app.ModuleBasics.BasicManager.ValidateGenesis(appState); err != nil {
+		return fmt.Errorf("invalid genesis after the edit: %v", err)
+	}
+	return nil
+}
+
+func genesisModulesOf(cdc *codec.Codec, genState app.GenesisState) map[string]json.RawMessage {
+	var modules map[string]json.RawMessage
+	if err := json.Unmarshal(cdc.MustMarshalJSON(genState), &modules); err != nil {
+		panic(err)
+	}
+	return modules
+}
+
+// moduleParams returns the params of the modules in the genesis state, which
+// are all the params in the params store
+func moduleParams(genState *app.GenesisState) map[string]params.ParamSet {
+	return map[string]params.ParamSet{
+		auth.ModuleName:       &genState.AuthData.Params,
+		authx.ModuleName:      &genState.AuthXData.Params,
+		bankx.ModuleName:      &genState.BankXData.Params,
+		staking.ModuleName:    &genState.StakingData.Params,
+		stakingx.ModuleName:   &genState.StakingXData.Params,
+		slashing.ModuleName:   &genState.SlashingData.Params,
+		asset.ModuleName:      &genState.AssetData.Params,
+		market.ModuleName:     &genState.MarketData.Params,
+		bancorlite.ModuleName: &genState.BancorData.Params,
+		alias.ModuleName:      &genState.AliasData.Params,
+		incentive.ModuleName:  &genState.Incentive.Params,
+	}
+}
+
+func setParam(cdc *codec.Codec, genState *app.GenesisState, module, key, value string) error {
+	paramSets := moduleParams(genState)
+	paramSet, ok := paramSets[module]
+	if !ok {
+		modules := make([]string, 0, len(paramSets))
+		for name := range paramSets {
+			modules = append(modules, name)
+		}
+		sort.Strings(modules)
+		return fmt.Errorf("no params of module %s, the modules with params are: %s", module, strings.Join(modules, ", "))
+	}
+
+	pairs := paramSet.ParamSetPairs()
+	keys := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if string(pair.Key) == key {
+			return unmarshalParam(cdc, value, pair.Value)
+		}
+		keys = append(keys, string(pair.Key))
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("no param %s of module %s, the params are: %s", key, module, strings.Join(keys, ", "))
+}
+
+// unmarshalParam decodes the value as amino JSON, taking it as a JSON string
+// when it is not a JSON value, as the numbers of sdk.Dec and int64
+func unmarshalParam(cdc *codec.Codec, value string, ptr interface{}) error {
+	v := reflect.New(reflect.TypeOf(ptr).Elem())
+	if err := cdc.UnmarshalJSON([]byte(value), v.Interface()); err != nil {
+		if cdc.UnmarshalJSON([]byte(strconv.Quote(value)), v.Interface()) != nil {
+			return fmt.Errorf("invalid value %s of type %s: %v", value, v.Elem().Type(), err)
+		}
+	}
+	reflect.ValueOf(ptr).Elem().Set(v.Elem())
+	return nil
+}
+
+func findToken(genState *app.GenesisState, symbol string) asset.Token {
+	for _, token := range genState.AssetData.Tokens {
+		if token.GetSymbol() == symbol {
+			return token
+		}
+	}
+	return nil
+}
+
+// checkTradingPair checks the tokens of a trading pair exist and returns the
+// owner of the stock
+func checkTradingPair(genState *app.GenesisState, stock, money string) (sdk.AccAddress, error) {
+	stockToken := findToken(genState, stock)
+	if stockToken == nil {
+		return nil, fmt.Errorf("no token %s in genesis", stock)
+	}
+	if findToken(genState, money) == nil {
+		return nil, fmt.Errorf("no token %s in genesis", money)
+	}
+	return stockToken.GetOwner(), nil
+}
+
+func addMarket(genState *app.GenesisState, stock, money string, pricePrecision, orderPrecision byte) error {
+	creator, err := checkTradingPair(genState, stock, money)
+	if err != nil {
+		return err
+	}
+	msg := market.MsgCreateTradingPair{
+		Stock:          stock,
+		Money:          money,
+		Creator:        creator,
+		PricePrecision: pricePrecision,
+		OrderPrecision: orderPrecision,
+	}
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	if orderPrecision > maxOrderPrecision {
+		return fmt.Errorf("order precision %d is larger than %d", orderPrecision, maxOrderPrecision)
+	}
+	symbol := market.GetSymbol(stock, money)
+	for _, info := range genState.MarketData.MarketInfos {
+		if info.GetSymbol() == symbol {
+			return fmt.Errorf("trading pair %s already exists", symbol)
+		}
+	}
+
+	genState.MarketData.MarketInfos = append(genState.MarketData.MarketInfos, market.MarketInfo{
+		Stock:             stock,
+		Money:             money,
+		PricePrecision:    pricePrecision,
+		LastExecutedPrice: sdk.ZeroDec(),
+		OrderPrecision:    orderPrecision,
+	})
+	return nil
+}
+
+// addBancor adds the bancor as bancor-init does, freezing the max supply of
+// the stock in the account of its owner, without the fee
+func addBancor(genState *app.GenesisState, msg bancorlite.MsgBancorInit) error {
+	// bancor-init would store a larger precision as 0
+	if msg.StockPrecision > maxStockPrecision {
+		return fmt.Errorf("stock precision %d is larger than %d", msg.StockPrecision, maxStockPrecision)
+	}
+	owner, err := checkTradingPair(genState, msg.Stock, msg.Money)
+	if err != nil {
+		return err
+	}
+	msg.Owner = owner
+	if err := msg.ValidateBasic(); err != nil {
+		return err
+	}
+	symbol := market.GetSymbol(msg.Stock, msg.Money)
+	if _, ok := genState.BancorData.BancorInfoMap[symbol]; ok {
+		return fmt.Errorf("bancor %s already exists", symbol)
+	}
+	initPrice, _ := sdk.NewDecFromStr(msg.InitPrice)
+	maxPrice, _ := sdk.NewDecFromStr(msg.MaxPrice)
+
+	supplied := sdk.NewCoins(sdk.NewCoin(msg.Stock, msg.MaxSupply))
+	if err := freezeGenesisCoins(genState, owner, supplied); err != nil {
+		return err
+	}
+	if genState.BancorData.BancorInfoMap == nil {
+		genState.BancorData.BancorInfoMap = make(map[string]bancorlite.BancorInfo)
+	}
+	genState.BancorData.BancorInfoMap[symbol] = bancorlite.BancorInfo{
+		Owner:              owner,
+		Stock:              msg.Stock,
+		Money:              msg.Money,
+		InitPrice:          initPrice,
+		MaxSupply:          msg.MaxSupply,
+		StockPrecision:     msg.StockPrecision,
+		MaxPrice:           maxPrice,
+		MaxMoney:           msg.MaxMoney,
+		AR:                 bancorAR(msg, initPrice, maxPrice),
+		Price:              initPrice,
+		StockInPool:        msg.MaxSupply,
+		MoneyInPool:        sdk.ZeroInt(),
+		EarliestCancelTime: msg.EarliestCancelTime,
+	}
+	return nil
+}
+
+// bancorAR is CalculateAR of bancorlite
+func bancorAR(msg bancorlite.MsgBancorInit, initPrice, maxPrice sdk.Dec) int64 {
+	if maxPrice.Equal(initPrice) {
+		return 0
+	}
+	if sdk.NewDecFromInt(msg.MaxMoney).LTE(initPrice.MulInt(msg.MaxSupply)) {
+		return 0
+	}
+	return maxPrice.MulInt(msg.MaxSupply).Sub(sdk.NewDecFromInt(msg.MaxMoney)).
+		QuoTruncate(sdk.NewDecFromInt(msg.MaxMoney).Sub(initPrice.MulInt(msg.MaxSupply))).
+		MulInt64(bancorARSamples).TruncateInt64()
+}
+
+// freezeGenesisCoins moves the coins of the genesis account to its frozen
+// coins, which are held by the module account of authx as bankx does
+func freezeGenesisCoins(genState *app.GenesisState, addr sdk.AccAddress, coins sdk.Coins) error {
+	accounts := genState.Accounts
+	i := findGenesisAccount(accounts, addr)
+	if i < 0 {
+		return fmt.Errorf("no account %s in genesis", addr)
+	}
+	left, hasNeg := accounts[i].Coins.SafeSub(coins)
+	if hasNeg {
+		return fmt.Errorf("account %s has %s, less than %s", addr, accounts[i].Coins, coins)
+	}
+	accounts[i].Coins = left
+
+	maccAddr := supply.NewModuleAddress(authx.ModuleName)
+	if j := findGenesisAccount(accounts, maccAddr); j >= 0 {
+		accounts[j].Coins = accounts[j].Coins.Add(coins)
+	} else {
+		accounts = append(accounts, genaccounts.GenesisAccount{
+			Address:           maccAddr,
+			Coins:             coins,
+			ModuleName:        authx.ModuleName,
+			ModulePermissions: app.MaccPerms[authx.ModuleName],
+		})
+	}
+	genState.Accounts = accounts
+
+	accountXs := genState.AuthXData.AccountXs
+	for k := range accountXs {
+		if accountXs[k].Address.Equals(addr) {
+			accountXs[k].FrozenCoins = accountXs[k].FrozenCoins.Add(coins)
+			return nil
+		}
+	}
+	genState.AuthXData.AccountXs = append(accountXs, authx.AccountX{Address: addr, FrozenCoins: coins})
+	return nil
+}
+
+func findGenesisAccount(accounts genaccounts.GenesisState, addr sdk.AccAddress) int {
+	for i, acc := range accounts {
+		if acc.Address.Equals(addr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// aliasEntry has the JSON of the alias entries, whose type is internal to alias
+type aliasEntry struct {
+	Alias     string         `json:"alias"`
+	Addr      sdk.AccAddress `json:"addr"`
+	AsDefault bool           `json:"is_default"`
+}
+
+func setAlias(cdc *codec.Codec, genState *app.GenesisState, name string, addr sdk.AccAddress, asDefault bool) error {
+	var entries []aliasEntry
+	cdc.MustUnmarshalJSON(cdc.MustMarshalJSON(genState.AliasData.AliasEntryList), &entries)
+
+	found, count := false, 0
+	for i := range entries {
+		if entries[i].Alias == name {
+			if !entries[i].Addr.Equals(addr) {
+				return fmt.Errorf("alias %s is already used by %s", name, entries[i].Addr)
+			}
+			entries[i].AsDefault = asDefault
+			found = true
+		} else if asDefault && entries[i].Addr.Equals(addr) {
+			entries[i].AsDefault = false
+		}
+		if entries[i].Addr.Equals(addr) {
+			count++
+		}
+	}
+	if !found {
+		if count >= genState.AliasData.Params.MaxAliasCount {
+			return fmt.Errorf("address %s already has %d aliases", addr, count)
+		}
+		entries = append(entries, aliasEntry{Alias: name, Addr: addr, AsDefault: asDefault})
+	}
+
+	genState.AliasData.AliasEntryList = nil
+	cdc.MustUnmarshalJSON(cdc.MustMarshalJSON(entries), &genState.AliasData.AliasEntryList)
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	tm "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/bancorlite"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+func newGenesisToken(t *testing.T, symbol string, supply int64, owner sdk.AccAddress) asset.Token {
+	token, err := asset.NewToken(symbol+" token", symbol, sdk.NewInt(supply), owner,
+		false, true, false, false, "www.coinex.org", "A test token", "552A83BA62F9B1F8")
+	require.Nil(t, err)
+	return token
+}
+
+func genesisStateForEdits(t *testing.T) (app.GenesisState, sdk.AccAddress) {
+	_, _, owner := testutil.KeyPubAddr()
+	genState := app.NewDefaultGenesisState()
+	genState.AssetData.Tokens = []asset.Token{
+		newGenesisToken(t, dex.CET, 1e10, owner),
+		newGenesisToken(t, "abc", 1e10, owner),
+	}
+	genState.Accounts = genaccounts.GenesisState{
+		genaccounts.NewGenesisAccountRaw(owner, sdk.NewCoins(sdk.NewInt64Coin(dex.CET, 1e10), sdk.NewInt64Coin("abc", 1e10)),
+			sdk.NewCoins(), 0, 0, "", ""),
+	}
+	return genState, owner
+}
+
+func editGenesisState(t *testing.T, cdc *codec.Codec, genState *app.GenesisState, edit genesisEdit) error {
+	appState := genesisModulesOf(cdc, *genState)
+	if err := editAppState(cdc, appState, edit); err != nil {
+		return err
+	}
+	*genState = app.FromMap(cdc, appState)
+	return nil
+}
+
+func TestSetParam(t *testing.T) {
+	cdc := app.MakeCodec()
+	genState, _ := genesisStateForEdits(t)
+
+	set := func(module, key, value string) error {
+		return editGenesisState(t, cdc, &genState, func(genState *app.GenesisState) error {
+			return setParam(cdc, genState, module, key, value)
+		})
+	}
+	require.Nil(t, set("market", "MarketFeeRate", "10"))
+	require.Nil(t, set("authx", "MinGasPriceLimit", "0.5"))
+	require.Nil(t, set("staking", "UnbondingTime", "3600000000000"))
+	require.Nil(t, set("alias", "MaxAliasCount", "3"))
+	require.Equal(t, int64(10), genState.MarketData.Params.MarketFeeRate)
+	require.Equal(t, sdk.NewDecWithPrec(5, 1), genState.AuthXData.Params.MinGasPriceLimit)
+	require.Equal(t, time.Hour, genState.StakingData.Params.UnbondingTime)
+	require.Equal(t, 3, genState.AliasData.Params.MaxAliasCount)
+
+	require.Contains(t, set("comment", "Fee", "1").Error(), "no params of module comment")
+	require.Contains(t, set("market", "NoSuchKey", "1").Error(), "no param NoSuchKey of module market")
+	require.Contains(t, set("market", "MarketFeeRate", "abc").Error(), "invalid value abc")
+
+	// rejected by the validation of market, and not applied
+	require.Contains(t, set("market", "MarketFeeRate", "-1").Error(), "invalid genesis after the edit")
+	require.Equal(t, int64(10), genState.MarketData.Params.MarketFeeRate)
+}
+
+func TestAddMarket(t *testing.T) {
+	genState, _ := genesisStateForEdits(t)
+
+	require.Nil(t, addMarket(&genState, "abc", dex.CET, 8, 2))
+	require.Equal(t, 1, len(genState.MarketData.MarketInfos))
+	info := genState.MarketData.MarketInfos[0]
+	require.Equal(t, "abc/cet", info.GetSymbol())
+	require.Equal(t, byte(8), info.PricePrecision)
+	require.Equal(t, byte(2), info.OrderPrecision)
+	require.True(t, info.LastExecutedPrice.IsZero())
+
+	require.Contains(t, addMarket(&genState, "abc", dex.CET, 8, 0).Error(), "already exists")
+	require.Contains(t, addMarket(&genState, "xyz", dex.CET, 8, 0).Error(), "no token xyz")
+	require.NotNil(t, addMarket(&genState, dex.CET, "abc", 19, 0))
+	require.Contains(t, addMarket(&genState, dex.CET, "abc", 8, 9).Error(), "order precision 9")
+}
+
+func TestAddBancor(t *testing.T) {
+	cdc := app.MakeCodec()
+	genState, owner := genesisStateForEdits(t)
+
+	msg := bancorlite.MsgBancorInit{
+		Stock:     "abc",
+		Money:     dex.CET,
+		InitPrice: "1",
+		MaxSupply: sdk.NewInt(1e9),
+		MaxPrice:  "10",
+		MaxMoney:  sdk.ZeroInt(),
+	}
+	require.Nil(t, editGenesisState(t, cdc, &genState, func(genState *app.GenesisState) error {
+		return addBancor(genState, msg)
+	}))
+	bi := genState.BancorData.BancorInfoMap["abc/cet"]
+	require.Equal(t, owner, bi.Owner)
+	require.Equal(t, sdk.NewInt(1e9), bi.StockInPool)
+	require.True(t, bi.IsConsistent())
+
+	// the max supply is frozen
+	require.Equal(t, sdk.NewInt(9e9), genState.Accounts[0].Coins.AmountOf("abc"))
+	require.Equal(t, 1, len(genState.AuthXData.AccountXs))
+	require.Equal(t, sdk.NewInt(1e9), genState.AuthXData.AccountXs[0].FrozenCoins.AmountOf("abc"))
+	require.Equal(t, 2, len(genState.Accounts))
+	require.Equal(t, sdk.NewInt(1e9), genState.Accounts[1].Coins.AmountOf("abc"))
+
+	genDoc := &tm.GenesisDoc{
+		GenesisTime: time.Unix(1500000000, 0).UTC(),
+		ChainID:     "c1",
+		AppState:    cdc.MustMarshalJSON(genState),
+	}
+	require.Empty(t, app.CheckGenesis(genDoc))
+
+	require.Contains(t, addBancor(&genState, msg).Error(), "already exists")
+	msg.StockPrecision = 9
+	require.Contains(t, addBancor(&genState, msg).Error(), "stock precision 9 is larger than 8")
+	msg.StockPrecision = 0
+	// rejected by the validation of bancorlite
+	msg.Stock, msg.Money = dex.CET, "abc"
+	err := editGenesisState(t, cdc, &genState, func(genState *app.GenesisState) error {
+		return addBancor(genState, msg)
+	})
+	require.Contains(t, err.Error(), "stock can not be cet")
+	msg.Stock, msg.Money = "xyz", dex.CET
+	require.Contains(t, addBancor(&genState, msg).Error(), "no token xyz")
+}
+
+func TestSetAlias(t *testing.T) {
+	cdc := app.MakeCodec()
+	genState, owner := genesisStateForEdits(t)
+	_, _, other := testutil.KeyPubAddr()
+
+	setAlias := func(name string, addr sdk.AccAddress, asDefault bool) error {
+		return editGenesisState(t, cdc, &genState, func(genState *app.GenesisState) error {
+			return setAlias(cdc, genState, name, addr, asDefault)
+		})
+	}
+	require.Nil(t, setAlias("super_boy", owner, true))
+	require.Nil(t, setAlias("super_man", owner, true))
+	require.Nil(t, setAlias("super_girl", other, false))
+
+	var entries []aliasEntry
+	cdc.MustUnmarshalJSON(cdc.MustMarshalJSON(genState.AliasData.AliasEntryList), &entries)
+	require.Equal(t, []aliasEntry{
+		{Alias: "super_boy", Addr: owner, AsDefault: false},
+		{Alias: "super_man", Addr: owner, AsDefault: true},
+		{Alias: "super_girl", Addr: other, AsDefault: false},
+	}, entries)
+
+	require.Contains(t, setAlias("super_boy", other, false).Error(), "already used by")
+	require.Contains(t, setAlias("a", owner, false).Error(), "invalid genesis after the edit")
+
+	genState.AliasData.Params.MaxAliasCount = 2
+	require.Contains(t, setAlias("super_star", owner, false).Error(), "already has 2 aliases")
+	require.Nil(t, setAlias("super_boy", owner, true))
+}
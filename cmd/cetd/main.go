@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"io"
+	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
@@ -21,6 +23,7 @@ import (
 	"github.com/cosmos/cosmos-sdk/codec"
 	"github.com/cosmos/cosmos-sdk/server"
 	"github.com/cosmos/cosmos-sdk/store"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/genaccounts"
 	genaccscli "github.com/cosmos/cosmos-sdk/x/genaccounts/client/cli"
 	genutilcli "github.com/cosmos/cosmos-sdk/x/genutil/client/cli"
@@ -68,6 +71,12 @@ func createCetdCmd() *cobra.Command {
 	addInitCommands(ctx, cdc, rootCmd)
 	rootCmd.AddCommand(client.NewCompletionCmd(rootCmd, true))
 	server.AddCommands(ctx, cdc, rootCmd, newApp, exportAppStateAndTMValidators)
+	overrideStartCmd(ctx, rootCmd)
+	overrideExportCmd(ctx, cdc, rootCmd)
+	rootCmd.AddCommand(exportStreamCmd(ctx))
+	rootCmd.AddCommand(exportTablesCmd(ctx))
+	rootCmd.AddCommand(stateDiffCmd(ctx))
+	rootCmd.AddCommand(debugCmd(ctx))
 
 	rootCmd.PersistentFlags().UintVar(&invCheckPeriod, flagInvCheckPeriod,
 		0, "Assert registered invariants every N blocks")
@@ -86,11 +95,16 @@ func addInitCommands(ctx *server.Context, cdc *codec.Codec, rootCmd *cobra.Comma
 	rootCmd.AddCommand(genutilcli.CollectGenTxsCmd(ctx, cdc, genaccounts.AppModuleBasic{}, app.DefaultNodeHome))
 	rootCmd.AddCommand(genutilcli.GenTxCmd(ctx, cdc, rawBasicManager, staking.AppModuleBasic{},
 		genaccounts.AppModuleBasic{}, app.DefaultNodeHome, app.DefaultCLIHome))
-	rootCmd.AddCommand(genutilcli.ValidateGenesisCmd(ctx, cdc, rawBasicManager))
+	rootCmd.AddCommand(validateGenesisCmd(ctx, cdc, rawBasicManager))
+	rootCmd.AddCommand(validateGenesisStreamCmd())
 	rootCmd.AddCommand(genaccscli.AddGenesisAccountCmd(ctx, cdc, app.DefaultNodeHome, app.DefaultCLIHome))
 	rootCmd.AddCommand(assetcli.AddGenesisTokenCmd(ctx, cdc, app.DefaultNodeHome, app.DefaultCLIHome))
+	rootCmd.AddCommand(genesisCmd(ctx, cdc))
 	rootCmd.AddCommand(testnetCmd(ctx, cdc, app.ModuleBasics, genaccounts.AppModuleBasic{}))
 	rootCmd.AddCommand(migrateCmd(cdc))
+	rootCmd.AddCommand(replayNotificationsCmd(ctx))
+	rootCmd.AddCommand(rollbackCmd(ctx, cdc))
+	rootCmd.AddCommand(tradeServerCmd(ctx))
 }
 
 func adjustBlockCommitSpeed(config *tmconfig.Config) {
@@ -100,6 +114,12 @@ func adjustBlockCommitSpeed(config *tmconfig.Config) {
 	c.PeerQueryMaj23SleepDuration = 100 * time.Millisecond
 }
 
+// the app metrics are registered to prometheus only once per process
+var (
+	metricsOnce sync.Once
+	appMetrics  *app.Metrics
+)
+
 func newApp(logger log.Logger, db dbm.DB, traceStore io.Writer) abci.Application {
 	cetChainApp := app.NewCetChainApp(
 		logger, db, traceStore, true, invCheckPeriod,
@@ -108,6 +128,12 @@ func newApp(logger log.Logger, db dbm.DB, traceStore io.Writer) abci.Application
 		baseapp.SetCheckTxWithMsgHandle(viper.GetBool(server.FlagCheckTxWithMsgHandle)),
 	)
 	checkMinGasPrice(cetChainApp, logger)
+	if viper.GetBool("instrumentation.prometheus") {
+		metricsOnce.Do(func() {
+			appMetrics = app.PrometheusMetrics(viper.GetString("instrumentation.namespace"))
+		})
+		cetChainApp.SetMetrics(appMetrics)
+	}
 	return cetChainApp
 }
 
@@ -134,3 +160,21 @@ func exportAppStateAndTMValidators(
 	gApp := app.NewCetChainApp(logger, db, traceStore, true, uint(1))
 	return gApp.ExportAppStateAndValidators(forZeroHeight, jailWhiteList)
 }
+
+// loadAppAtHeight loads the application state at height, or the latest one if
+// height is -1, from the application DB under the root of ctx.Config, which the
+// caller closes
+func loadAppAtHeight(ctx *server.Context, height int64) (*app.CetChainApp, dbm.DB, error) {
+	db, err := sdk.NewLevelDB("application", filepath.Join(ctx.Config.RootDir, "data"))
+	if err != nil {
+		return nil, nil, err
+	}
+	gApp := app.NewCetChainApp(ctx.Logger, db, nil, height == -1, uint(1))
+	if height != -1 {
+		if err := gApp.LoadHeight(height); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+	}
+	return gApp, db, nil
+}
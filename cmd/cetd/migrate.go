@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -10,12 +11,6 @@ import (
 	tm "github.com/tendermint/tendermint/types"
 
 	"github.com/cosmos/cosmos-sdk/codec"
-	sdk "github.com/cosmos/cosmos-sdk/types"
-
-	"github.com/coinexchain/cet-sdk/modules/asset"
-	"github.com/coinexchain/cet-sdk/modules/authx"
-	"github.com/coinexchain/cet-sdk/modules/market"
-	"github.com/coinexchain/dex/app"
 )
 
 const (
@@ -23,17 +18,23 @@ const (
 	flagListValidators = "list-validators"
 	GenesisBlockHeight = "genesis-block-height"
 	flagGenesisTime    = "genesis-time"
+	flagDryRun         = "dry-run"
 )
 
 func migrateCmd(cdc *codec.Codec) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "migrate [from]",
-		Short: "Migrate genesis.json (coinexdex -> coinexdex2)",
-		Args:  cobra.ExactArgs(1),
+		Use:   "migrate [target-version] [genesis-file]",
+		Short: "Migrate genesis.json to a chain version, e.g. coinexdex2",
+		Long: `Migrate genesis.json from its chain version, i.e. its chain ID, to the target version.
+The migrations registered between the versions are applied in order.`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			inputFile := args[0]
+			if err := checkGenesisTimeFlag(cmd); err != nil {
+				return err
+			}
+			target, inputFile := args[0], args[1]
 			outputFile := viper.GetString(flagOutput)
-			return migrateGenesisFile(cdc, inputFile, outputFile)
+			return migrateGenesisFile(cdc, target, inputFile, outputFile)
 		},
 	}
 
@@ -41,34 +42,53 @@ func migrateCmd(cdc *codec.Codec) *cobra.Command {
 	cmd.Flags().Int64(flagGenesisTime, 0, "The unix timestamp for genesis time, in seconds")
 	cmd.Flags().String(flagOutput, "", "New genesis.json file")
 	cmd.Flags().Bool(flagListValidators, false, "List validators in genesis.json file")
-
-	cmd.MarkFlagRequired(flagGenesisTime)
+	cmd.Flags().Bool(flagDryRun, false, "Print what each migration step changes, without writing the new genesis.json")
 	return cmd
 }
 
-func migrateGenesisFile(cdc *codec.Codec, inputFile, outputFile string) error {
+// the genesis time is not used by a dry run, which writes no genesis.json
+func checkGenesisTimeFlag(cmd *cobra.Command) error {
+	if viper.GetBool(flagDryRun) || cmd.Flags().Changed(flagGenesisTime) {
+		return nil
+	}
+	return fmt.Errorf("required flag \"%s\" not set", flagGenesisTime)
+}
+
+func migrateGenesisFile(cdc *codec.Codec, target, inputFile, outputFile string) error {
 	data, err := ioutil.ReadFile(inputFile)
 	if err != nil {
 		return err
 	}
 
 	genDoc := &tm.GenesisDoc{}
-	cdc.MustUnmarshalJSON(data, genDoc)
+	if err = cdc.UnmarshalJSON(data, genDoc); err != nil {
+		return err
+	}
 	if viper.GetBool(flagListValidators) {
 		listValidators(genDoc)
 		return nil
 	}
 	genesisTime := viper.GetInt64(flagGenesisTime)
 
-	genState := &app.GenesisState{}
-	cdc.MustUnmarshalJSON(genDoc.AppState, genState)
-
-	upgradeGenesisState(genState)
+	migrations, err := findMigrationPath(genDoc.ChainID, target)
+	if err != nil {
+		return err
+	}
+	var reports []*migrationReport
+	genDoc.AppState, reports, err = migrateAppState(genDoc.AppState, migrations)
+	if err != nil {
+		return err
+	}
+	if viper.GetBool(flagDryRun) {
+		for _, report := range reports {
+			report.write(os.Stdout)
+		}
+		return nil
+	}
 
-	genDoc.ChainID = "coinexdex2"
+	genDoc.ChainID = target
 	genDoc.GenesisBlockHeight = viper.GetInt64(GenesisBlockHeight)
 	genDoc.GenesisTime = time.Unix(genesisTime, 0)
-	genDoc.AppState = cdc.MustMarshalJSON(genState)
 	data = cdc.MustMarshalJSON(genDoc)
 
 	if outputFile == "" {
@@ -77,25 +97,3 @@ func migrateGenesisFile(cdc *codec.Codec, inputFile, outputFile string) error {
 	}
 	return ioutil.WriteFile(outputFile, data, 0644)
 }
-
-func upgradeGenesisState(genState *app.GenesisState) {
-	genState.GovData.VotingParams.VotingPeriod = app.VotingPeriod
-	genState.StakingXData.Params.MinSelfDelegation = app.MinSelfDelegation
-	genState.AuthXData.Params = authx.DefaultParams()
-	genState.AssetData.Params = asset.DefaultParams()
-	genState.MarketData.Params = market.DefaultParams()
-	for _, v := range genState.MarketData.Orders {
-		if v.FrozenFee != 0 {
-			v.FrozenCommission = v.FrozenFee
-			v.FrozenFee = 0
-		}
-	}
-	for k, v := range genState.BancorData.BancorInfoMap {
-		if v.AR == 0 {
-			v.MaxMoney = sdk.ZeroInt()
-			genState.BancorData.BancorInfoMap[k] = v
-		}
-	}
-	genState.Incentive.State.HeightAdjustment = 0
-	// TODO: more upgrades
-}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
+	tm "github.com/tendermint/tendermint/types"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
 
@@ -13,7 +20,26 @@ import (
 	"github.com/coinexchain/dex/app"
 )
 
+func readMigrationFixture(t *testing.T, m genesisMigration, name string) json.RawMessage {
+	bz, err := ioutil.ReadFile(filepath.Join("testdata", "migrations", m.From+"-"+m.To, name))
+	require.Nil(t, err, "every migration needs the fixtures %s", name)
+	return bz
+}
+
+// TestMigrationFixtures checks every migration by its fixtures, input.json
+// and expected.json in testdata/migrations/<from>-<to>
+func TestMigrationFixtures(t *testing.T) {
+	for _, m := range genesisMigrations {
+		input := readMigrationFixture(t, m, "input.json")
+		expected := readMigrationFixture(t, m, "expected.json")
+		output, _, err := migrateAppState(input, []genesisMigration{m})
+		require.Nil(t, err, m.To)
+		require.JSONEq(t, string(expected), string(output), m.To)
+	}
+}
+
 func TestMigrate(t *testing.T) {
+	cdc := app.MakeCodec()
 	state := app.NewDefaultGenesisState()
 
 	// simulate DEX1
@@ -24,7 +50,12 @@ func TestMigrate(t *testing.T) {
 	state.BancorData.BancorInfoMap["x"] = bancorlite.BancorInfo{}
 
 	// upgrade to DEX2
-	upgradeGenesisState(&state)
+	migrations, err := findMigrationPath("coinexdex", "coinexdex2")
+	require.Nil(t, err)
+	appState, _, err := migrateAppState(cdc.MustMarshalJSON(state), migrations)
+	require.Nil(t, err)
+	state = app.GenesisState{}
+	cdc.MustUnmarshalJSON(appState, &state)
 
 	// check state
 	require.Equal(t, time.Hour*24*7, state.GovData.VotingParams.VotingPeriod)
@@ -40,5 +71,149 @@ func TestMigrate(t *testing.T) {
 	require.EqualValues(t, 1e10, state.MarketData.Params.CreateMarketFee)
 	require.EqualValues(t, 200000, state.MarketData.Params.GTEOrderLifetime)
 	require.EqualValues(t, 100, state.MarketData.Orders[0].FrozenCommission)
+	require.EqualValues(t, 0, state.MarketData.Orders[0].FrozenFee)
 	require.Equal(t, sdk.ZeroInt(), state.BancorData.BancorInfoMap["x"].MaxMoney)
+
+	// the DEX2 params in the migration are the defaults of this version
+	require.Equal(t, app.NewDefaultGenesisState().AuthXData.Params, state.AuthXData.Params)
+	require.Equal(t, app.NewDefaultGenesisState().AssetData.Params, state.AssetData.Params)
+	require.Equal(t, app.NewDefaultGenesisState().MarketData.Params, state.MarketData.Params)
+}
+
+func TestMigrationReport(t *testing.T) {
+	m := genesisMigrations[0]
+	_, reports, err := migrateAppState(readMigrationFixture(t, m, "input.json"), []genesisMigration{m})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(reports))
+	report := reports[0]
+
+	params := make(map[string]paramChange)
+	for _, p := range report.Params {
+		params[p.Path] = p
+	}
+	require.Equal(t, paramChange{"gov/voting_params/voting_period", `"172800000000000"`, `"604800000000000"`},
+		params["gov/voting_params/voting_period"])
+	require.Equal(t, paramChange{"authx/params/rebate_ratio", "", `"2000"`}, params["authx/params/rebate_ratio"])
+	require.Equal(t, paramChange{"market/params/create_market_fee", `"100000000000000"`, `"10000000000"`},
+		params["market/params/create_market_fee"])
+	_, ok := params["stakingx/params/min_mandatory_commission_rate"]
+	require.False(t, ok)
+
+	require.Equal(t, []migrationCount{
+		{"orders with frozen_fee moved to frozen_commission", 1},
+		{"bancors with max_money reset to 0", 1},
+	}, report.Counts)
+	supply := map[string]string{"abc": "5000", "cet": "588800000000000000"}
+	require.Equal(t, supply, report.SupplyBefore)
+	require.Equal(t, supply, report.SupplyAfter)
+
+	var buf bytes.Buffer
+	report.write(&buf)
+	out := buf.String()
+	require.Contains(t, out, "Migration coinexdex -> coinexdex2\n")
+	require.Contains(t, out, `    gov/voting_params/voting_period: "172800000000000" -> "604800000000000"`)
+	require.Contains(t, out, `    authx/params/rebate_ratio: (none) -> "2000"`)
+	require.Contains(t, out, "    orders with frozen_fee moved to frozen_commission: 1\n")
+	require.Contains(t, out, "    cet: 588800000000000000 -> 588800000000000000\n")
+}
+
+func TestFindMigrationPath(t *testing.T) {
+	defer func(migrations []genesisMigration) {
+		genesisMigrations = migrations
+	}(genesisMigrations)
+	genesisMigrations = []genesisMigration{
+		{From: "v1", To: "v2"},
+		{From: "v2", To: "v3"},
+		{From: "v1", To: "v1b"},
+		{From: "v3", To: "v4"},
+		{From: "v1b", To: "v4"},
+	}
+	versions := func(path []genesisMigration) []string {
+		var to []string
+		for _, m := range path {
+			to = append(to, m.To)
+		}
+		return to
+	}
+
+	path, err := findMigrationPath("v1", "v3")
+	require.Nil(t, err)
+	require.Equal(t, []string{"v2", "v3"}, versions(path))
+	path, err = findMigrationPath("v1", "v4")
+	require.Nil(t, err)
+	require.Equal(t, []string{"v1b", "v4"}, versions(path))
+	path, err = findMigrationPath("v2", "v4")
+	require.Nil(t, err)
+	require.Equal(t, []string{"v3", "v4"}, versions(path))
+
+	_, err = findMigrationPath("v3", "v1")
+	require.NotNil(t, err)
+	_, err = findMigrationPath("v0", "v1")
+	require.NotNil(t, err)
+	_, err = findMigrationPath("v1", "v1")
+	require.NotNil(t, err)
+}
+
+func TestMigrateGenesisFile(t *testing.T) {
+	cdc := app.MakeCodec()
+	dir, err := ioutil.TempDir("", "migrate")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	m := genesisMigrations[0]
+	genDoc := tm.GenesisDoc{ChainID: m.From, AppState: readMigrationFixture(t, m, "input.json")}
+	inputFile := filepath.Join(dir, "genesis.json")
+	outputFile := filepath.Join(dir, "migrated.json")
+	require.Nil(t, ioutil.WriteFile(inputFile, cdc.MustMarshalJSON(genDoc), 0644))
+
+	viper.Set(flagGenesisTime, 1577836800)
+	viper.Set(GenesisBlockHeight, 100)
+	defer viper.Reset()
+	require.NotNil(t, migrateGenesisFile(cdc, "unknown", inputFile, outputFile))
+	require.Nil(t, migrateGenesisFile(cdc, m.To, inputFile, outputFile))
+
+	bz, err := ioutil.ReadFile(outputFile)
+	require.Nil(t, err)
+	var migrated tm.GenesisDoc
+	cdc.MustUnmarshalJSON(bz, &migrated)
+	require.Equal(t, m.To, migrated.ChainID)
+	require.EqualValues(t, 100, migrated.GenesisBlockHeight)
+	require.Equal(t, int64(1577836800), migrated.GenesisTime.Unix())
+	require.JSONEq(t, string(readMigrationFixture(t, m, "expected.json")), string(migrated.AppState))
+
+	// nothing is written by a dry run
+	viper.Set(flagDryRun, true)
+	require.Nil(t, os.Remove(outputFile))
+	require.Nil(t, migrateGenesisFile(cdc, m.To, inputFile, outputFile))
+	_, err = os.Stat(outputFile)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestMigrateDex1Bancor(t *testing.T) {
+	bancor := jsonObject{"bancor_info_map": jsonObject{
+		"a": jsonObject{"ar": "0", "max_money": "500"},
+		"b": jsonObject{"ar": "0", "max_money": "0"},
+		"c": jsonObject{"ar": "10", "max_money": "500"},
+	}}
+	report := &migrationReport{}
+	require.Nil(t, migrateDex1Bancor(bancor, report))
+	// only the bancors whose max_money changes are counted
+	require.Equal(t, []migrationCount{{"bancors with max_money reset to 0", 1}}, report.Counts)
+	infos := bancor["bancor_info_map"].(jsonObject)
+	require.Equal(t, "0", infos["a"].(jsonObject)["max_money"])
+	require.Equal(t, "0", infos["b"].(jsonObject)["max_money"])
+	require.Equal(t, "500", infos["c"].(jsonObject)["max_money"])
+}
+
+func TestMigrateGenesisTimeFlag(t *testing.T) {
+	defer viper.Reset()
+	cmd := migrateCmd(app.MakeCodec())
+	require.Nil(t, cmd.Flags().Parse(nil))
+	require.NotNil(t, checkGenesisTimeFlag(cmd))
+	viper.Set(flagDryRun, true)
+	require.Nil(t, checkGenesisTimeFlag(cmd))
+
+	viper.Set(flagDryRun, false)
+	require.Nil(t, cmd.Flags().Parse([]string{"--" + flagGenesisTime, "1577836800"}))
+	require.Nil(t, checkGenesisTimeFlag(cmd))
 }
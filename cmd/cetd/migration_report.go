@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// migrationReport describes what a migration step changes, for the review of
+// a migration by `cetd migrate --dry-run`
+type migrationReport struct {
+	From   string
+	To     string
+	Params []paramChange
+	Counts []migrationCount
+	// the total supply of every denom, before and after the step
+	SupplyBefore map[string]string
+	SupplyAfter  map[string]string
+}
+
+// paramChange is a changed param, Old or New is empty if it is added or removed
+type paramChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+// migrationCount is the number of the entries changed by a step in some way
+type migrationCount struct {
+	Name string
+	N    int
+}
+
+func (report *migrationReport) addCount(name string, n int) {
+	report.Counts = append(report.Counts, migrationCount{name, n})
+}
+
+// isParamsKey tells whether a key of a module holds params, e.g. "params" and
+// "voting_params" of gov
+func isParamsKey(key string) bool {
+	return key == "params" || strings.HasSuffix(key, "_params")
+}
+
+func decodeJSONObject(bz []byte) (jsonObject, error) {
+	var obj jsonObject
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	err := dec.Decode(&obj)
+	return obj, err
+}
+
+// flattenJSON adds the leaves of v to leaves by their paths, the arrays are
+// leaves
+func flattenJSON(leaves map[string]string, path string, v interface{}) {
+	if obj, ok := v.(jsonObject); ok {
+		for key, child := range obj {
+			flattenJSON(leaves, path+"/"+key, child)
+		}
+		return
+	}
+	bz, _ := json.Marshal(v)
+	leaves[path] = string(bz)
+}
+
+// collectParams returns the params of all the modules by their paths
+func collectParams(modules genesisModules) map[string]string {
+	params := make(map[string]string)
+	for name, bz := range modules {
+		module, err := decodeJSONObject(bz)
+		if err != nil {
+			continue
+		}
+		for key, v := range module {
+			if isParamsKey(key) {
+				flattenJSON(params, name+"/"+key, v)
+			}
+		}
+	}
+	return params
+}
+
+func diffParams(before, after map[string]string) []paramChange {
+	var changes []paramChange
+	for path, old := range before {
+		if after[path] != old {
+			changes = append(changes, paramChange{path, old, after[path]})
+		}
+	}
+	for path, v := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, paramChange{path, "", v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// collectSupply returns the total supply of every denom in the supply module
+func collectSupply(modules genesisModules) (map[string]string, error) {
+	totals := make(map[string]*big.Int)
+	bz, ok := modules["supply"]
+	if ok {
+		var supply struct {
+			Supply []struct {
+				Denom  string `json:"denom"`
+				Amount string `json:"amount"`
+			} `json:"supply"`
+		}
+		if err := json.Unmarshal(bz, &supply); err != nil {
+			return nil, fmt.Errorf("invalid supply: %v", err)
+		}
+		for _, coin := range supply.Supply {
+			amount, ok := new(big.Int).SetString(coin.Amount, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid supply of %s: %s", coin.Denom, coin.Amount)
+			}
+			if totals[coin.Denom] == nil {
+				totals[coin.Denom] = new(big.Int)
+			}
+			totals[coin.Denom].Add(totals[coin.Denom], amount)
+		}
+	}
+	result := make(map[string]string, len(totals))
+	for denom, total := range totals {
+		result[denom] = total.String()
+	}
+	return result, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (report *migrationReport) write(w io.Writer) {
+	fmt.Fprintf(w, "Migration %s -> %s\n", report.From, report.To)
+
+	fmt.Fprintf(w, "  Params changed: %d\n", len(report.Params))
+	for _, p := range report.Params {
+		old, v := p.Old, p.New
+		if old == "" {
+			old = "(none)"
+		}
+		if v == "" {
+			v = "(removed)"
+		}
+		fmt.Fprintf(w, "    %s: %s -> %s\n", p.Path, old, v)
+	}
+
+	if len(report.Counts) > 0 {
+		fmt.Fprintf(w, "  Entries changed:\n")
+		for _, c := range report.Counts {
+			fmt.Fprintf(w, "    %s: %d\n", c.Name, c.N)
+		}
+	}
+
+	denoms := make(map[string]string)
+	for denom := range report.SupplyBefore {
+		denoms[denom] = denom
+	}
+	for denom := range report.SupplyAfter {
+		denoms[denom] = denom
+	}
+	fmt.Fprintf(w, "  Supply:\n")
+	for _, denom := range sortedKeys(denoms) {
+		before, after := report.SupplyBefore[denom], report.SupplyAfter[denom]
+		mark := ""
+		if before != after {
+			mark = " (changed)"
+		}
+		fmt.Fprintf(w, "    %s: %s -> %s%s\n", denom, orZero(before), orZero(after), mark)
+	}
+}
+
+func orZero(amount string) string {
+	if amount == "" {
+		return "0"
+	}
+	return amount
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// A genesis migration upgrades the app state of a genesis doc from one chain
+// version, i.e. the chain ID, to another. The types of app.GenesisState change
+// among the versions, so a migration works on the JSON of the modules and
+// must not use the types of the modules, e.g. their default params.
+
+type genesisModules map[string]json.RawMessage
+
+type jsonObject = map[string]interface{}
+
+type genesisMigration struct {
+	From    string
+	To      string
+	Migrate func(modules genesisModules, report *migrationReport) error
+}
+
+var genesisMigrations = []genesisMigration{
+	{From: "coinexdex", To: "coinexdex2", Migrate: migrateDex1ToDex2},
+}
+
+// findMigrationPath returns the shortest chain of migrations from one version
+// to another
+func findMigrationPath(from, to string) ([]genesisMigration, error) {
+	if from == to {
+		return nil, fmt.Errorf("the genesis is already of version %s", to)
+	}
+	paths := map[string][]genesisMigration{from: nil}
+	queue := []string{from}
+	for len(queue) > 0 {
+		version := queue[0]
+		queue = queue[1:]
+		for _, m := range genesisMigrations {
+			if m.From != version {
+				continue
+			}
+			if _, ok := paths[m.To]; ok {
+				continue
+			}
+			path := append(append([]genesisMigration(nil), paths[version]...), m)
+			if m.To == to {
+				return path, nil
+			}
+			paths[m.To] = path
+			queue = append(queue, m.To)
+		}
+	}
+	return nil, fmt.Errorf("no migration from %s to %s", from, to)
+}
+
+// migrateAppState applies the migrations to the app state in order, the
+// modules not changed by them are kept as they are. It returns the report of
+// every step.
+func migrateAppState(appState json.RawMessage, migrations []genesisMigration) (
+	json.RawMessage, []*migrationReport, error) {
+
+	var modules genesisModules
+	if err := json.Unmarshal(appState, &modules); err != nil {
+		return nil, nil, err
+	}
+	reports := make([]*migrationReport, len(migrations))
+	for i, m := range migrations {
+		report := &migrationReport{From: m.From, To: m.To}
+		params := collectParams(modules)
+		var err error
+		if report.SupplyBefore, err = collectSupply(modules); err != nil {
+			return nil, nil, err
+		}
+		if err = m.Migrate(modules, report); err != nil {
+			return nil, nil, fmt.Errorf("failed to migrate from %s to %s: %v", m.From, m.To, err)
+		}
+		report.Params = diffParams(params, collectParams(modules))
+		if report.SupplyAfter, err = collectSupply(modules); err != nil {
+			return nil, nil, err
+		}
+		reports[i] = report
+	}
+	appState, err := json.Marshal(modules)
+	return appState, reports, err
+}
+
+// editModule decodes the JSON of a module, changes it by edit and encodes it
+// back, the missing modules are skipped
+func (modules genesisModules) editModule(name string, edit func(module jsonObject) error) error {
+	bz, ok := modules[name]
+	if !ok {
+		return nil
+	}
+	module, err := decodeJSONObject(bz)
+	if err != nil {
+		return fmt.Errorf("invalid module %s: %v", name, err)
+	}
+	if err := edit(module); err != nil {
+		return fmt.Errorf("module %s: %v", name, err)
+	}
+	bz, err = json.Marshal(module)
+	if err != nil {
+		return err
+	}
+	modules[name] = bz
+	return nil
+}
+
+// getObject returns the object under key, which is added if missing
+func getObject(obj jsonObject, key string) (jsonObject, error) {
+	switch v := obj[key].(type) {
+	case jsonObject:
+		return v, nil
+	case nil:
+		child := make(jsonObject)
+		obj[key] = child
+		return child, nil
+	default:
+		return nil, fmt.Errorf("%s is not an object", key)
+	}
+}
+
+func setValue(obj jsonObject, value interface{}, path ...string) error {
+	for _, key := range path[:len(path)-1] {
+		var err error
+		if obj, err = getObject(obj, key); err != nil {
+			return err
+		}
+	}
+	obj[path[len(path)-1]] = value
+	return nil
+}
+
+// isZero tells whether an integer in amino JSON, which is a string, is zero
+// or missing
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == "" || v == "0"
+	case json.Number:
+		return v.String() == "0"
+	}
+	return false
+}
+
+// the params of DEX2 which replace the ones of DEX1
+var (
+	dex2AuthXParams = json.RawMessage(`{"min_gas_price_limit":"20.000000000000000000",` +
+		`"referee_change_min_interval":"604800000000000","rebate_ratio":"2000"}`)
+	dex2AssetParams = json.RawMessage(`{"issue_token_fee":"5000000000","issue_rare_token_fee":"1000000000000",` +
+		`"issue_3char_token_fee":"100000000000","issue_4char_token_fee":"50000000000",` +
+		`"issue_5char_token_fee":"20000000000","issue_6char_token_fee":"10000000000"}`)
+	dex2MarketParams = json.RawMessage(`{"create_market_fee":"10000000000","market_min_expired_time":"604800000000000",` +
+		`"gte_order_lifetime":"200000","gte_order_feature_fee_by_blocks":"10",` +
+		`"max_executed_price_change_ratio":"25","market_fee_rate":"10","market_fee_min":"1000000",` +
+		`"fee_for_zero_deal":"1000000"}`)
+)
+
+const (
+	dex2VotingPeriod      = "604800000000000" // 7 days
+	dex2MinSelfDelegation = "100000000000000"
+)
+
+func migrateDex1ToDex2(modules genesisModules, report *migrationReport) error {
+	edits := []struct {
+		module string
+		edit   func(module jsonObject) error
+	}{
+		{"gov", func(gov jsonObject) error {
+			return setValue(gov, dex2VotingPeriod, "voting_params", "voting_period")
+		}},
+		{"stakingx", func(stakingx jsonObject) error {
+			return setValue(stakingx, dex2MinSelfDelegation, "params", "min_self_delegation")
+		}},
+		{"authx", func(authx jsonObject) error {
+			return setValue(authx, dex2AuthXParams, "params")
+		}},
+		{"asset", func(asset jsonObject) error {
+			return setValue(asset, dex2AssetParams, "params")
+		}},
+		{"market", func(market jsonObject) error {
+			return migrateDex1Market(market, report)
+		}},
+		{"bancorlite", func(bancor jsonObject) error {
+			return migrateDex1Bancor(bancor, report)
+		}},
+		{"incentive", func(incentive jsonObject) error {
+			return setValue(incentive, "0", "state", "height_adjustment")
+		}},
+	}
+	for _, e := range edits {
+		if err := modules.editModule(e.module, e.edit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the frozen fee of an order is renamed to frozen commission in DEX2
+func migrateDex1Market(market jsonObject, report *migrationReport) error {
+	if err := setValue(market, dex2MarketParams, "params"); err != nil {
+		return err
+	}
+	orders, _ := market["orders"].([]interface{})
+	moved := 0
+	for i, v := range orders {
+		order, ok := v.(jsonObject)
+		if !ok {
+			return fmt.Errorf("order %d is not an object", i)
+		}
+		if !isZero(order["frozen_fee"]) {
+			order["frozen_commission"] = order["frozen_fee"]
+			delete(order, "frozen_fee")
+			moved++
+		}
+	}
+	report.addCount("orders with frozen_fee moved to frozen_commission", moved)
+	return nil
+}
+
+// the max money of a bancor without AR is zero in DEX2
+func migrateDex1Bancor(bancor jsonObject, report *migrationReport) error {
+	infos, _ := bancor["bancor_info_map"].(jsonObject)
+	reset := 0
+	for key, v := range infos {
+		info, ok := v.(jsonObject)
+		if !ok {
+			return fmt.Errorf("bancor %s is not an object", key)
+		}
+		if !isZero(info["ar"]) {
+			continue
+		}
+		if !isZero(info["max_money"]) {
+			reset++
+		}
+		info["max_money"] = "0"
+	}
+	report.addCount("bancors with max_money reset to 0", reset)
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/store/cachekv"
+	"github.com/cosmos/cosmos-sdk/store/dbadapter"
+)
+
+var _ dbm.DB = (*overlayDB)(nil)
+
+// overlayDB reads through to a parent database but keeps all the writes
+// in memory, so that an app can be loaded and executed on top of a node's
+// data directory without modifying it.
+type overlayDB struct {
+	cache *cachekv.Store
+}
+
+func newOverlayDB(parent dbm.DB) *overlayDB {
+	return &overlayDB{cache: cachekv.NewStore(dbadapter.Store{DB: parent})}
+}
+
+func (db *overlayDB) Get(key []byte) []byte {
+	return db.cache.Get(key)
+}
+
+func (db *overlayDB) Has(key []byte) bool {
+	return db.cache.Has(key)
+}
+
+func (db *overlayDB) Set(key, value []byte) {
+	db.cache.Set(key, value)
+}
+
+func (db *overlayDB) SetSync(key, value []byte) {
+	db.cache.Set(key, value)
+}
+
+func (db *overlayDB) Delete(key []byte) {
+	db.cache.Delete(key)
+}
+
+func (db *overlayDB) DeleteSync(key []byte) {
+	db.cache.Delete(key)
+}
+
+func (db *overlayDB) Iterator(start, end []byte) dbm.Iterator {
+	return db.cache.Iterator(start, end)
+}
+
+func (db *overlayDB) ReverseIterator(start, end []byte) dbm.Iterator {
+	return db.cache.ReverseIterator(start, end)
+}
+
+// Close does nothing, the parent database is owned by the caller
+func (db *overlayDB) Close() {}
+
+func (db *overlayDB) NewBatch() dbm.Batch {
+	return &overlayBatch{db: db}
+}
+
+func (db *overlayDB) Print() {
+	itr := db.Iterator(nil, nil)
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+}
+
+func (db *overlayDB) Stats() map[string]string {
+	return map[string]string{"database.type": "overlayDB"}
+}
+
+type overlayOp struct {
+	del   bool
+	key   []byte
+	value []byte
+}
+
+type overlayBatch struct {
+	db  *overlayDB
+	ops []overlayOp
+}
+
+func (b *overlayBatch) Set(key, value []byte) {
+	b.ops = append(b.ops, overlayOp{key: key, value: value})
+}
+
+func (b *overlayBatch) Delete(key []byte) {
+	b.ops = append(b.ops, overlayOp{del: true, key: key})
+}
+
+func (b *overlayBatch) Write() {
+	for _, op := range b.ops {
+		if op.del {
+			b.db.Delete(op.key)
+		} else {
+			b.db.Set(op.key, op.value)
+		}
+	}
+}
+
+func (b *overlayBatch) WriteSync() {
+	b.Write()
+}
+
+func (b *overlayBatch) Close() {
+	b.ops = nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestOverlayDB(t *testing.T) {
+	parent := dbm.NewMemDB()
+	parent.Set([]byte("a"), []byte("1"))
+	parent.Set([]byte("b"), []byte("2"))
+
+	db := newOverlayDB(parent)
+	require.Equal(t, []byte("1"), db.Get([]byte("a")))
+
+	db.Set([]byte("c"), []byte("3"))
+	db.Delete([]byte("a"))
+	batch := db.NewBatch()
+	batch.Set([]byte("b"), []byte("22"))
+	batch.Write()
+	batch.Close()
+
+	require.False(t, db.Has([]byte("a")))
+	require.Equal(t, []byte("22"), db.Get([]byte("b")))
+	require.Equal(t, []byte("3"), db.Get([]byte("c")))
+
+	var keys []string
+	itr := db.Iterator(nil, nil)
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	itr.Close()
+	require.Equal(t, []string{"b", "c"}, keys)
+
+	// the parent is never modified
+	require.Equal(t, []byte("1"), parent.Get([]byte("a")))
+	require.Equal(t, []byte("2"), parent.Get([]byte("b")))
+	require.False(t, parent.Has([]byte("c")))
+}
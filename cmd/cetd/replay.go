@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	abcicli "github.com/tendermint/tendermint/abci/client"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	tmstore "github.com/tendermint/tendermint/store"
+	tmtypes "github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/cet-sdk/msgqueue"
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagReplayFrom   = "from"
+	flagReplayTo     = "to"
+	flagReplaySink   = "sink"
+	flagReplayTopics = "subscribe-modules"
+
+	defaultReplayTopics = "auth,authx,bank,bankx,comment,distr,market,bancorlite,staking,slashing"
+)
+
+func replayNotificationsCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay-notifications",
+		Short: "Re-execute stored blocks and write the pub messages they produce to a sink",
+		Long: `Load the application state at height (from-1), re-execute the blocks [from, to]
+stored in Tendermint's block store with the msg queue forced on, and write the
+produced messages (notify_tx, height_info, slash ...) to the given sink. The
+first block can not be replayed, the state before it is built by InitChain from
+the genesis file and is not committed.
+
+All the writes done during the replay are kept in memory, so the node's data
+directory is left untouched. The node must be stopped while replaying.
+
+Example:
+$ cetd replay-notifications --from 100 --to 200 --sink file:/tmp/replay.txt
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(flags.FlagHome))
+
+			from := viper.GetInt64(flagReplayFrom)
+			to := viper.GetInt64(flagReplayTo)
+			sink := viper.GetString(flagReplaySink)
+			if from <= 0 || to < from {
+				return fmt.Errorf("invalid height range [%d, %d]", from, to)
+			}
+			if strings.HasPrefix(sink, msgqueue.CfgPrefixPrune) {
+				return fmt.Errorf("sink %s is not supported for replaying", sink)
+			}
+
+			blockStoreDB := dbm.NewDB("blockstore", dbm.DBBackendType(config.DBBackend), config.DBDir())
+			defer blockStoreDB.Close()
+			stateDB := dbm.NewDB("state", dbm.DBBackendType(config.DBBackend), config.DBDir())
+			defer stateDB.Close()
+			appDB, err := sdk.NewLevelDB("application", filepath.Join(config.RootDir, "data"))
+			if err != nil {
+				return err
+			}
+			defer appDB.Close()
+
+			return replayNotifications(ctx.Logger, appDB, tmstore.NewBlockStore(blockStoreDB), stateDB,
+				from, to, sink, viper.GetString(flagReplayTopics))
+		},
+	}
+
+	cmd.Flags().Int64(flagReplayFrom, 0, "The first height to replay")
+	cmd.Flags().Int64(flagReplayTo, 0, "The last height to replay")
+	cmd.Flags().String(flagReplaySink, "", "Where to write the messages, e.g. file:/tmp/replay.txt or kafka:coinex-dex@localhost:9092")
+	cmd.Flags().String(flagReplayTopics, defaultReplayTopics, "Modules whose messages are published")
+	_ = cmd.MarkFlagRequired(flagReplayFrom)
+	_ = cmd.MarkFlagRequired(flagReplayTo)
+	_ = cmd.MarkFlagRequired(flagReplaySink)
+	return cmd
+}
+
+func replayNotifications(logger log.Logger, appDB dbm.DB, blockStore *tmstore.BlockStore, stateDB dbm.DB,
+	from, to int64, sink, topics string) error {
+
+	if to > blockStore.Height() {
+		return fmt.Errorf("height %d is not in the block store, latest height is %d", to, blockStore.Height())
+	}
+	// the state before the first block is the one of InitChain, which is not committed
+	if from <= tmtypes.GenesisBlockHeight+1 {
+		return fmt.Errorf("can not replay from height %d, there is no committed state before it, start from %d",
+			from, tmtypes.GenesisBlockHeight+2)
+	}
+
+	// force the msg queue on, app.NewCetChainApp creates its producer from these settings
+	viper.Set(msgqueue.FlagBrokers, []string{sink})
+	viper.Set(msgqueue.FlagTopics, topics)
+	viper.Set(msgqueue.FlagFeatureToggle, true)
+	viper.Set(app.FlagPubSubscription, "")
+
+	cetApp := app.NewCetChainApp(logger, newOverlayDB(appDB), nil, false, 0)
+	defer cetApp.CloseMsgQue()
+	if err := cetApp.LoadHeight(from - 1); err != nil {
+		return fmt.Errorf("failed to load app state at height %d: %v", from-1, err)
+	}
+
+	appConn := proxy.NewAppConnConsensus(abcicli.NewLocalClient(new(sync.Mutex), cetApp))
+	for h := from; h <= to; h++ {
+		block := blockStore.LoadBlock(h)
+		if block == nil {
+			return fmt.Errorf("block %d not found", h)
+		}
+		appHash, err := sm.ExecCommitBlock(appConn, block, logger, stateDB)
+		if err != nil {
+			return fmt.Errorf("failed to execute block %d: %v", h, err)
+		}
+		if next := blockStore.LoadBlockMeta(h + 1); next != nil && !bytes.Equal(next.Header.AppHash, appHash) {
+			logger.Error("app hash mismatch", "height", h,
+				"expected", next.Header.AppHash, "got", fmt.Sprintf("%X", appHash))
+		}
+		logger.Info("replayed block", "height", h, "txs", len(block.Txs))
+	}
+	return nil
+}
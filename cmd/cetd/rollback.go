@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	sm "github.com/tendermint/tendermint/state"
+	tmstore "github.com/tendermint/tendermint/store"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+func rollbackCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll the application state, the Tendermint state and the block store back to a height",
+		Long: `Reset the multistore to the version of the given height, deleting the later
+versions, and make the Tendermint state and the block store consistent with it:
+the later blocks are deleted and the consensus WAL is moved aside, so the node
+syncs the following blocks again when it is started.
+
+The height must not be below the pruning horizon, i.e. every height from it to
+the latest one must be kept by the --pruning strategy of the node. The node
+must be stopped while rolling back. A validator's priv_validator_state.json is
+left untouched, so it refuses to sign again the heights it has signed.
+
+Example:
+$ cetd rollback --height 1000
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(flags.FlagHome))
+			height := viper.GetInt64(flagHeight)
+
+			blockStoreDB := dbm.NewDB("blockstore", dbm.DBBackendType(config.DBBackend), config.DBDir())
+			defer blockStoreDB.Close()
+			stateDB := dbm.NewDB("state", dbm.DBBackendType(config.DBBackend), config.DBDir())
+			defer stateDB.Close()
+			appDB, err := sdk.NewLevelDB("application", filepath.Join(config.RootDir, "data"))
+			if err != nil {
+				return err
+			}
+			defer appDB.Close()
+
+			blockStore := tmstore.NewBlockStore(blockStoreDB)
+			state := sm.LoadState(stateDB)
+			rolledBack, err := rollbackState(cdc, stateDB, blockStore, state, height)
+			if err != nil {
+				return err
+			}
+			gApp := app.NewCetChainApp(ctx.Logger, appDB, nil, false, uint(1))
+			if err := gApp.LoadHeight(height); err != nil {
+				return fmt.Errorf("failed to load app state at height %d: %v", height, err)
+			}
+			if !bytes.Equal(gApp.LastCommitID().Hash, rolledBack.AppHash) {
+				return fmt.Errorf("the app hash %X at height %d is not the one in block %d: %X",
+					gApp.LastCommitID().Hash, height, height+1, rolledBack.AppHash)
+			}
+
+			result, err := app.RollbackStores(appDB, height)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("application state rolled back from height %d to %d, app hash %X\n",
+				result.FromHeight, result.ToHeight, result.AppHash)
+
+			sm.SaveState(stateDB, rolledBack)
+			fmt.Printf("tendermint state rolled back from height %d to %d\n", state.LastBlockHeight, height)
+
+			storeHeight := blockStore.Height()
+			deleteBlocks(blockStoreDB, blockStore, height)
+			fmt.Printf("blocks %d to %d deleted from the block store\n", height+1, storeHeight)
+
+			walDir := filepath.Dir(config.Consensus.WalFile())
+			if _, err := os.Stat(walDir); err == nil {
+				movedTo := fmt.Sprintf("%s.rollback-%d", walDir, storeHeight)
+				if err := os.Rename(walDir, movedTo); err != nil {
+					return err
+				}
+				fmt.Printf("consensus WAL moved to %s\n", movedTo)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64(flagHeight, 0, "The height to roll back to")
+	_ = cmd.MarkFlagRequired(flagHeight)
+	return cmd
+}
+
+// see tendermint/state/store.go
+func calcValidatorsKey(height int64) []byte {
+	return []byte(fmt.Sprintf("validatorsKey:%v", height))
+}
+
+func calcConsensusParamsKey(height int64) []byte {
+	return []byte(fmt.Sprintf("consensusParamsKey:%v", height))
+}
+
+// rollbackState returns the Tendermint state after the block at the given
+// height was committed, which is built from the validators and the consensus
+// params saved in the state db, and the headers of the block and the next one
+func rollbackState(cdc *codec.Codec, stateDB dbm.DB, blockStore *tmstore.BlockStore,
+	state sm.State, height int64) (sm.State, error) {
+
+	if height <= 0 || height >= state.LastBlockHeight {
+		return state, fmt.Errorf("height %d is not below the latest height %d", height, state.LastBlockHeight)
+	}
+	meta, next := blockStore.LoadBlockMeta(height), blockStore.LoadBlockMeta(height+1)
+	if meta == nil || next == nil {
+		return state, fmt.Errorf("block %d or %d is not in the block store", height, height+1)
+	}
+
+	rolledBack := state.Copy()
+	rolledBack.LastBlockHeight = height
+	rolledBack.LastBlockTotalTx = meta.Header.TotalTxs
+	rolledBack.LastBlockID = meta.BlockID
+	rolledBack.LastBlockTime = meta.Header.Time
+	rolledBack.LastResultsHash = next.Header.LastResultsHash
+	rolledBack.AppHash = next.Header.AppHash
+
+	var err error
+	if rolledBack.LastValidators, err = sm.LoadValidators(stateDB, height); err != nil {
+		return state, err
+	}
+	if rolledBack.Validators, err = sm.LoadValidators(stateDB, height+1); err != nil {
+		return state, err
+	}
+	if rolledBack.NextValidators, err = sm.LoadValidators(stateDB, height+2); err != nil {
+		return state, err
+	}
+	var valsInfo sm.ValidatorsInfo
+	if err := cdc.UnmarshalBinaryBare(stateDB.Get(calcValidatorsKey(height+2)), &valsInfo); err != nil {
+		return state, fmt.Errorf("failed to load validators info at height %d: %v", height+2, err)
+	}
+	rolledBack.LastHeightValidatorsChanged = valsInfo.LastHeightChanged
+	if !bytes.Equal(rolledBack.Validators.Hash(), next.Header.ValidatorsHash) {
+		return state, fmt.Errorf("the validators at height %d are not the ones in block %d", height+1, height+1)
+	}
+
+	if rolledBack.ConsensusParams, err = sm.LoadConsensusParams(stateDB, height+1); err != nil {
+		return state, err
+	}
+	var paramsInfo sm.ConsensusParamsInfo
+	if err := cdc.UnmarshalBinaryBare(stateDB.Get(calcConsensusParamsKey(height+1)), &paramsInfo); err != nil {
+		return state, fmt.Errorf("failed to load consensus params info at height %d: %v", height+1, err)
+	}
+	rolledBack.LastHeightConsensusParamsChanged = paramsInfo.LastHeightChanged
+	return rolledBack, nil
+}
+
+// see tendermint/store/store.go#SaveBlock()
+func deleteBlocks(db dbm.DB, blockStore *tmstore.BlockStore, height int64) {
+	batch := db.NewBatch()
+	defer batch.Close()
+	for h := height + 1; h <= blockStore.Height(); h++ {
+		if meta := blockStore.LoadBlockMeta(h); meta != nil {
+			for i := 0; i < meta.BlockID.PartsHeader.Total; i++ {
+				batch.Delete([]byte(fmt.Sprintf("P:%v:%v", h, i)))
+			}
+		}
+		batch.Delete([]byte(fmt.Sprintf("H:%v", h)))
+		batch.Delete([]byte(fmt.Sprintf("C:%v", h-1)))
+		batch.Delete([]byte(fmt.Sprintf("SC:%v", h)))
+	}
+	batch.WriteSync()
+	tmstore.BlockStoreStateJSON{Height: height}.Save(db)
+	blockStore.SetHeight(height)
+}
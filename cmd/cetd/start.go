@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/p2p"
+	pvm "github.com/tendermint/tendermint/privval"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/cet-sdk/msgqueue"
+	"github.com/coinexchain/dex/app"
+)
+
+// flags of the start command defined by cosmos-sdk/server
+const (
+	flagWithTendermint = "with-tendermint"
+	flagTraceStore     = "trace-store"
+	flagCPUProfile     = "cpu-profile"
+)
+
+// overrideStartCmd replaces the in-process mode of the `start` command added by
+// server.AddCommands, so that the embedded trade-server follows the node's lifecycle,
+// and rejects the trade-server in the standalone mode
+func overrideStartCmd(ctx *server.Context, rootCmd *cobra.Command) {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() != "start" {
+			continue
+		}
+		cmd.Flags().Duration(app.FlagSlowBlockThreshold, 0,
+			"Log the per-module profile of the blocks slower than this duration, e.g. 500ms")
+		cmd.Flags().String(app.FlagTxDecoder, app.TxDecoderAmino,
+			"The tx decoder, amino or codon, which decodes the same txs faster")
+		cmd.Flags().Bool(app.FlagLogStoreHashes, false,
+			"Log the commit hash of each mounted store after every commit")
+		startStandAlone := cmd.RunE
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if !viper.GetBool(flagWithTendermint) {
+				if err := checkStandAlone(); err != nil {
+					return err
+				}
+				return startStandAlone(cmd, args)
+			}
+			ctx.Logger.Info("starting ABCI with Tendermint")
+			return startInProcess(ctx)
+		}
+	}
+}
+
+// checkStandAlone rejects the embedded trade-server when the ABCI app runs
+// without Tendermint, which has no node for the trade-server to follow
+func checkStandAlone() error {
+	if app.IsTradeServerEnabled() {
+		return fmt.Errorf("the embedded trade-server needs --%s=true, or remove the %s broker from app.toml",
+			flagWithTendermint, msgqueue.CfgPrefixPrune)
+	}
+	return nil
+}
+
+// see cosmos-sdk/server/start.go#startInProcess()
+func startInProcess(ctx *server.Context) error {
+	cfg := ctx.Config
+	home := cfg.RootDir
+
+	db, err := sdk.NewLevelDB("application", filepath.Join(home, "data"))
+	if err != nil {
+		return err
+	}
+	traceWriter, err := openTraceWriter(viper.GetString(flagTraceStore))
+	if err != nil {
+		return err
+	}
+
+	cetChainApp := newApp(ctx.Logger, db, traceWriter).(*app.CetChainApp)
+
+	nodeKey, err := p2p.LoadOrGenNodeKey(cfg.NodeKeyFile())
+	if err != nil {
+		return err
+	}
+
+	server.UpgradeOldPrivValFile(cfg)
+
+	// create & start tendermint node
+	tmNode, err := node.NewNode(
+		cfg,
+		pvm.LoadOrGenFilePV(cfg.PrivValidatorKeyFile(), cfg.PrivValidatorStateFile()),
+		nodeKey,
+		cetChainApp.NewLocalClientCreator(),
+		node.DefaultGenesisDocProviderFunc(cfg),
+		node.DefaultDBProvider,
+		node.DefaultMetricsProvider(cfg.Instrumentation),
+		ctx.Logger.With("module", "node"),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := cetChainApp.StartTradeServer(rpcclient.NewLocal(tmNode)); err != nil {
+		return err
+	}
+
+	if err := tmNode.Start(); err != nil {
+		cetChainApp.StopTradeServer()
+		return err
+	}
+
+	var cpuProfileCleanup func()
+
+	if cpuProfile := viper.GetString(flagCPUProfile); cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return err
+		}
+
+		ctx.Logger.Info("starting CPU profiler", "profile", cpuProfile)
+		if err := pprof.StartCPUProfile(f); err != nil {
+			return err
+		}
+
+		cpuProfileCleanup = func() {
+			ctx.Logger.Info("stopping CPU profiler", "profile", cpuProfile)
+			pprof.StopCPUProfile()
+			f.Close()
+		}
+	}
+
+	server.TrapSignal(func() {
+		if tmNode.IsRunning() {
+			_ = tmNode.Stop()
+		}
+
+		// no more blocks are committed, flush what has been published
+		cetChainApp.StopTradeServer()
+		cetChainApp.CloseMsgQue()
+
+		if cpuProfileCleanup != nil {
+			cpuProfileCleanup()
+		}
+
+		ctx.Logger.Info("exiting...")
+	})
+
+	// run forever (the node will not be returned)
+	select {}
+}
+
+func openTraceWriter(traceWriterFile string) (w io.Writer, err error) {
+	if traceWriterFile != "" {
+		w, err = os.OpenFile(
+			traceWriterFile,
+			os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+			0666,
+		)
+		return
+	}
+	return
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinexchain/cet-sdk/msgqueue"
+)
+
+func TestCheckStandAlone(t *testing.T) {
+	defer viper.Reset()
+	require.Nil(t, checkStandAlone())
+
+	viper.Set(msgqueue.FlagBrokers, []string{msgqueue.CfgPrefixPrune + "/tmp/data"})
+	err := checkStandAlone()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "--with-tendermint")
+}
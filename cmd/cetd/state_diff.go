@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagDiffFrom = "from"
+	flagDiffTo   = "to"
+)
+
+func stateDiffCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state-diff",
+		Short: "Print the keys of the KV stores added, removed or modified between two heights",
+		Long: `Load the application state at both heights and walk every mounted KV store in
+key order, printing each key added, removed or modified from the first height to
+the second as a line of JSON. The keys are hex strings, and the values of the
+accounts, tokens, orders, markets, bancors, validators, delegations and params
+are decoded into JSON, the other ones are hex strings.
+
+Only the heights kept by the --pruning strategy of the node can be loaded.
+
+Example:
+$ cetd state-diff --from 100 --to 101
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := ctx.Config
+			config.SetRoot(viper.GetString(flags.FlagHome))
+
+			fromHeight := viper.GetInt64(flagDiffFrom)
+			toHeight := viper.GetInt64(flagDiffTo)
+			if fromHeight <= 0 || toHeight <= 0 {
+				return fmt.Errorf("invalid heights %d and %d", fromHeight, toHeight)
+			}
+
+			db, err := sdk.NewLevelDB("application", filepath.Join(config.RootDir, "data"))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			from := app.NewCetChainApp(ctx.Logger, db, nil, false, uint(1))
+			if err := from.LoadHeight(fromHeight); err != nil {
+				return err
+			}
+			to := app.NewCetChainApp(ctx.Logger, db, nil, false, uint(1))
+			if err := to.LoadHeight(toHeight); err != nil {
+				return err
+			}
+
+			counts := make(map[string]int)
+			var writeErr error
+			app.StateDiff(from, to, func(change app.StoreChange) bool {
+				bz, err := json.Marshal(change)
+				if err != nil {
+					writeErr = err
+					return true
+				}
+				fmt.Println(string(bz))
+				counts[change.Type]++
+				return false
+			})
+			if writeErr != nil {
+				return writeErr
+			}
+			fmt.Fprintf(os.Stderr, "%d keys added, %d removed, %d modified\n",
+				counts[app.ChangeAdded], counts[app.ChangeRemoved], counts[app.ChangeModified])
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64(flagDiffFrom, 0, "The height to diff from")
+	cmd.Flags().Int64(flagDiffTo, 0, "The height to diff to")
+	_ = cmd.MarkFlagRequired(flagDiffFrom)
+	_ = cmd.MarkFlagRequired(flagDiffTo)
+	return cmd
+}
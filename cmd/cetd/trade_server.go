@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/server"
+
+	"github.com/coinexchain/dex/app"
+)
+
+func tradeServerCmd(ctx *server.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trade-server",
+		Short: "Embedded trade-server utilities",
+	}
+	cmd.AddCommand(checkTradeServerConfigCmd(ctx))
+	return cmd
+}
+
+func checkTradeServerConfigCmd(ctx *server.Context) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check-config",
+		Short: "Validate config/trade-server.toml before launching the node",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx.Config.SetRoot(viper.GetString(flags.FlagHome))
+			if _, err := app.ReadTradeServerConfig(); err != nil {
+				return err
+			}
+			if !app.IsTradeServerEnabled() {
+				fmt.Println("trade-server.toml is valid, but no prune broker is configured in app.toml, the trade-server will not be started")
+				return nil
+			}
+			fmt.Println("trade-server.toml is valid")
+			return nil
+		},
+	}
+}
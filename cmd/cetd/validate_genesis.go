@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	"github.com/cosmos/cosmos-sdk/types/module"
+	genutilcli "github.com/cosmos/cosmos-sdk/x/genutil/client/cli"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const flagDeep = "deep"
+
+// validateGenesisCmd is the validate-genesis of genutil, which only runs the
+// ValidateGenesis of every module, with a --deep mode starting an in-memory
+// app from the genesis
+func validateGenesisCmd(ctx *server.Context, cdc *codec.Codec, mbm module.BasicManager) *cobra.Command {
+	cmd := genutilcli.ValidateGenesisCmd(ctx, cdc, mbm)
+	validate := cmd.RunE
+	cmd.Long = `Validate the genesis file at the default location or at the location passed as an arg.
+
+With --deep, the genesis is loaded into an in-memory app by InitChain, then every
+registered invariant is asserted, and the total supply, the module accounts and the
+frozen coins of the orders and the bancors are cross-checked. All the violations found
+are reported, not only the first one.
+`
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if !viper.GetBool(flagDeep) {
+			return validate(cmd, args)
+		}
+
+		genesis := ctx.Config.GenesisFile()
+		if len(args) != 0 {
+			genesis = args[0]
+		}
+		fmt.Fprintf(os.Stderr, "validating genesis file at %s deeply\n", genesis)
+
+		genDoc, err := tmtypes.GenesisDocFromFile(genesis)
+		if err != nil {
+			return fmt.Errorf("error loading genesis doc from %s: %s", genesis, err.Error())
+		}
+		violations := app.CheckGenesis(genDoc)
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+		if len(violations) != 0 {
+			return fmt.Errorf("%d violations found in genesis file %s", len(violations), genesis)
+		}
+		fmt.Printf("File at %s is a valid genesis file\n", genesis)
+		return nil
+	}
+	cmd.Flags().Bool(flagDeep, false, "Start an in-memory app from the genesis and check its invariants and balances")
+	return cmd
+}
@@ -0,0 +1,300 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	amino "github.com/tendermint/go-amino"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The helpers of the generated amino encoders and decoders, which read and
+// write the binary encoding of amino without reflection. The decoders accept
+// the inputs written by amino, the other ones, e.g. with unknown fields, may
+// be rejected even if amino accepts them.
+
+const (
+	aminoTyp3Varint     = 0
+	aminoTyp3ByteLength = 2
+
+	// the range of the seconds of the times encoded by amino
+	aminoMinSeconds = -62135596800
+	aminoMaxSeconds = 253402300800
+)
+
+// aminoZeroTime is the time set by amino to the absent time fields
+var aminoZeroTime = time.Unix(0, 0).UTC()
+
+var (
+	errAminoField    = errors.New("amino: unexpected field")
+	errAminoLeftOver = errors.New("amino: bytes left over")
+	errAminoOverflow = errors.New("amino: integer overflows its type")
+	errAminoTime     = errors.New("amino: time out of range")
+)
+
+func errAminoUnregistered(v interface{}) error {
+	return fmt.Errorf("amino: unregistered type %T", v)
+}
+
+func errAminoNilPointer(v interface{}) error {
+	return fmt.Errorf("amino: nil pointer %T", v)
+}
+
+func aminoEncodeUvarint(w *bytes.Buffer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	w.Write(buf[:n])
+}
+
+func aminoEncodeVarint(w *bytes.Buffer, v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	w.Write(buf[:n])
+}
+
+func aminoEncodeBool(w *bytes.Buffer, v bool) {
+	if v {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func aminoEncodeBytes(w *bytes.Buffer, v []byte) {
+	aminoEncodeUvarint(w, uint64(len(v)))
+	w.Write(v)
+}
+
+func aminoEncodeString(w *bytes.Buffer, v string) {
+	aminoEncodeUvarint(w, uint64(len(v)))
+	w.WriteString(v)
+}
+
+// aminoEncodeTime writes the length-prefixed time, whose fields are the
+// seconds and the nanoseconds
+func aminoEncodeTime(w *bytes.Buffer, v time.Time) error {
+	s, ns := v.Unix(), int64(v.Nanosecond())
+	var buf [2 + 2*binary.MaxVarintLen64]byte
+	n := 0
+	if s != 0 {
+		if s < aminoMinSeconds || s >= aminoMaxSeconds {
+			return errAminoTime
+		}
+		buf[n] = 1<<3 | aminoTyp3Varint
+		n++
+		n += binary.PutUvarint(buf[n:], uint64(s))
+	}
+	if ns != 0 {
+		buf[n] = 2<<3 | aminoTyp3Varint
+		n++
+		n += binary.PutUvarint(buf[n:], uint64(ns))
+	}
+	aminoEncodeBytes(w, buf[:n])
+	return nil
+}
+
+func aminoEncodeInt(w *bytes.Buffer, v sdk.Int) error {
+	s, err := v.MarshalAmino()
+	if err != nil {
+		return err
+	}
+	aminoEncodeString(w, s)
+	return nil
+}
+
+func aminoEncodeDec(w *bytes.Buffer, v sdk.Dec) error {
+	s, err := v.MarshalAmino()
+	if err != nil {
+		return err
+	}
+	aminoEncodeString(w, s)
+	return nil
+}
+
+// aminoPrefixLength inserts the length of the bytes written since start
+// before them
+func aminoPrefixLength(w *bytes.Buffer, start int) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(w.Len()-start))
+	w.Write(buf[:n])
+	bz := w.Bytes()
+	copy(bz[start+n:], bz[start:len(bz)-n])
+	copy(bz[start:], buf[:n])
+}
+
+// aminoRollback removes the field written since mark if its value, written
+// since start, is a single zero byte, as amino does for the empty structs
+func aminoRollback(w *bytes.Buffer, mark, start int) {
+	if w.Len() == start+1 && w.Bytes()[start] == 0 {
+		w.Truncate(mark)
+	}
+}
+
+// aminoReadKey reads the key of the field num, it returns false if the field
+// is absent, i.e. bz is empty or starts with a later field
+func aminoReadKey(bz []byte, num, typ3 uint64) (bool, int, error) {
+	if len(bz) == 0 {
+		return false, 0, nil
+	}
+	key, n, err := aminoDecodeUvarint(bz)
+	if err != nil {
+		return false, 0, err
+	}
+	if key>>3 > num {
+		return false, 0, nil
+	}
+	if key != num<<3|typ3 {
+		return false, 0, errAminoField
+	}
+	return true, n, nil
+}
+
+// aminoReadLength returns the length-prefixed bytes at the start of bz, which
+// are not copied, and the count of bytes read
+func aminoReadLength(bz []byte) ([]byte, int, error) {
+	length, n, err := aminoDecodeUvarint(bz)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length > uint64(len(bz)-n) {
+		return nil, 0, ErrNotEnoughBytes
+	}
+	return bz[n : n+int(length)], n + int(length), nil
+}
+
+func aminoDecodeUvarint(bz []byte) (uint64, int, error) {
+	v, n := binary.Uvarint(bz)
+	if n == 0 {
+		return 0, 0, ErrNotEnoughBytes
+	} else if n < 0 {
+		return 0, 0, ErrVarintOverflow
+	}
+	return v, n, nil
+}
+
+func aminoDecodeVarint(bz []byte) (int64, int, error) {
+	v, n := binary.Varint(bz)
+	if n == 0 {
+		return 0, 0, ErrNotEnoughBytes
+	} else if n < 0 {
+		return 0, 0, ErrVarintOverflow
+	}
+	return v, n, nil
+}
+
+func aminoDecodeInt64(bz []byte) (int64, int, error) {
+	v, n, err := aminoDecodeUvarint(bz)
+	return int64(v), n, err
+}
+
+func aminoDecodeInt32(bz []byte) (int32, int, error) {
+	v, n, err := aminoDecodeInt64(bz)
+	if err == nil && (v < math.MinInt32 || v > math.MaxInt32) {
+		err = errAminoOverflow
+	}
+	return int32(v), n, err
+}
+
+func aminoDecodeInt16(bz []byte) (int16, int, error) {
+	v, n, err := aminoDecodeVarint(bz)
+	if err == nil && (v < math.MinInt16 || v > math.MaxInt16) {
+		err = errAminoOverflow
+	}
+	return int16(v), n, err
+}
+
+func aminoDecodeInt8(bz []byte) (int8, int, error) {
+	v, n, err := aminoDecodeVarint(bz)
+	if err == nil && (v < math.MinInt8 || v > math.MaxInt8) {
+		err = errAminoOverflow
+	}
+	return int8(v), n, err
+}
+
+func aminoDecodeUint64(bz []byte) (uint64, int, error) {
+	return aminoDecodeUvarint(bz)
+}
+
+func aminoDecodeUint32(bz []byte) (uint32, int, error) {
+	v, n, err := aminoDecodeUvarint(bz)
+	if err == nil && v > math.MaxUint32 {
+		err = errAminoOverflow
+	}
+	return uint32(v), n, err
+}
+
+func aminoDecodeUint16(bz []byte) (uint16, int, error) {
+	v, n, err := aminoDecodeUvarint(bz)
+	if err == nil && v > math.MaxUint16 {
+		err = errAminoOverflow
+	}
+	return uint16(v), n, err
+}
+
+func aminoDecodeUint8(bz []byte) (uint8, int, error) {
+	v, n, err := aminoDecodeUvarint(bz)
+	if err == nil && v > math.MaxUint8 {
+		err = errAminoOverflow
+	}
+	return uint8(v), n, err
+}
+
+func aminoDecodeBool(bz []byte) (bool, int, error) {
+	if len(bz) == 0 {
+		return false, 0, ErrNotEnoughBytes
+	}
+	if bz[0] > 1 {
+		return false, 0, ErrNonCanonical
+	}
+	return bz[0] == 1, 1, nil
+}
+
+// aminoDecodeBytes returns a copy of the length-prefixed bytes, or nil if
+// they are empty, as amino does
+func aminoDecodeBytes(bz []byte) ([]byte, int, error) {
+	v, n, err := aminoReadLength(bz)
+	if err != nil || len(v) == 0 {
+		return nil, n, err
+	}
+	return append([]byte(nil), v...), n, nil
+}
+
+func aminoDecodeString(bz []byte) (string, int, error) {
+	v, n, err := aminoReadLength(bz)
+	return string(v), n, err
+}
+
+func aminoDecodeTime(bz []byte) (time.Time, int, error) {
+	v, n, err := aminoReadLength(bz)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	t, m, err := amino.DecodeTime(v)
+	if err == nil && m != len(v) {
+		err = errAminoLeftOver
+	}
+	return t, n, err
+}
+
+func aminoDecodeInt(bz []byte) (sdk.Int, int, error) {
+	var v sdk.Int
+	s, n, err := aminoDecodeString(bz)
+	if err == nil {
+		err = v.UnmarshalAmino(s)
+	}
+	return v, n, err
+}
+
+func aminoDecodeDec(bz []byte) (sdk.Dec, int, error) {
+	var v sdk.Dec
+	s, n, err := aminoDecodeString(bz)
+	if err == nil {
+		err = v.UnmarshalAmino(s)
+	}
+	return v, n, err
+}
@@ -0,0 +1,102 @@
+package codec_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinexchain/dex/app"
+	"github.com/coinexchain/dex/codec"
+)
+
+var registeredTypes = getRegisteredTypes()
+
+func getRegisteredTypes() map[reflect.Type]bool {
+	// GetRegisteredTypes changes the codec
+	registered, err := codec.GetRegisteredTypes(app.MakeCodec())
+	if err != nil {
+		panic(err)
+	}
+	types := make(map[reflect.Type]bool, len(registered))
+	for _, rt := range registered {
+		types[reflect.TypeOf(rt.Value)] = true
+	}
+	return types
+}
+
+// aminoMarshal returns the error of amino, which panics on some values, e.g.
+// the nil pointers in interfaces
+func aminoMarshal(v interface{}) (bz []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("amino panics: %v", r)
+		}
+	}()
+	return aminoCdc.MarshalBinaryBare(v)
+}
+
+// requireSameAmino returns false if amino can not encode v
+func requireSameAmino(t *testing.T, v interface{}) bool {
+	aminoBytes, aminoErr := aminoMarshal(v)
+	var w bytes.Buffer
+	err := codec.EncodeAminoAny(&w, v)
+	if aminoErr != nil {
+		// e.g. a time after year 9999
+		require.NotNil(t, err, "%T", v)
+		return false
+	}
+	require.Nil(t, err, "%T", v)
+	require.Equal(t, aminoBytes, w.Bytes(), "%T", v)
+
+	if !registeredTypes[reflect.TypeOf(v)] {
+		// the types which are not registered have no prefix bytes
+		return true
+	}
+	decoded, err := codec.DecodeAminoAny(aminoBytes)
+	require.Nil(t, err, "%T", v)
+	ptr := reflect.New(reflect.TypeOf(decoded))
+	require.Nil(t, aminoCdc.UnmarshalBinaryBare(aminoBytes, ptr.Interface()))
+	require.Equal(t, ptr.Elem().Interface(), decoded)
+	return true
+}
+
+func TestAminoAny(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	compared := make(map[string]bool)
+	for i := 0; i < 20000; i++ {
+		v := codec.RandAny(r)
+		if requireSameAmino(t, v) {
+			rt := reflect.TypeOf(v)
+			compared[rt.PkgPath()+"."+rt.Name()] = true
+		}
+	}
+	for _, name := range codec.GetSupportList() {
+		require.True(t, compared[name] || interfaceNames[name], name)
+	}
+}
+
+func TestDecodeAminoAnyMalformed(t *testing.T) {
+	r := codec.NewRandSrc(1)
+	for i := 0; i < 300; i++ {
+		bz, err := aminoMarshal(codec.RandAny(r))
+		if err != nil {
+			continue
+		}
+		for j := 0; j < len(bz); j++ {
+			for _, malformed := range [][]byte{bz[:j], append(append([]byte{}, bz[:j]...), bz[j]^0x81)} {
+				decoded, err := codec.DecodeAminoAny(malformed)
+				if err != nil {
+					continue
+				}
+				// the accepted inputs are decoded as amino does
+				ptr := reflect.New(reflect.TypeOf(decoded))
+				if aminoCdc.UnmarshalBinaryBare(malformed, ptr.Interface()) == nil {
+					require.Equal(t, ptr.Elem().Interface(), decoded)
+				}
+			}
+		}
+	}
+}
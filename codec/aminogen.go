@@ -0,0 +1,541 @@
+package codec
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/coinexchain/codon"
+)
+
+// aminoCtx generates the encoders and the decoders of the binary encoding of
+// amino, whose structs are written as protobuf messages: the fields numbered
+// from 1 are written with their keys unless they are default, the lists of
+// the length-prefixed elements are unpacked into repeated fields, and the
+// concrete types in interfaces are prefixed by their prefix bytes.
+type aminoCtx struct {
+	*typeTable
+	prefixes map[reflect.Type][]byte
+	// the types registered as pointers, which amino decodes to pointers
+	pointers map[reflect.Type]bool
+	lines    []string
+	// the statement returning err in the function being generated
+	errReturn string
+}
+
+func (ctx *aminoCtx) add(format string, args ...interface{}) {
+	ctx.lines = append(ctx.lines, fmt.Sprintf(format, args...))
+}
+
+func (ctx *aminoCtx) addCall(format string, args ...interface{}) {
+	ctx.add("if err := "+format+"; err != nil {"+ctx.errReturn+"}", args...)
+}
+
+// addRead adds the call reading a value into x and advancing bz
+func (ctx *aminoCtx) addRead(format string, args ...interface{}) {
+	ctx.add("x, n, err := "+format, args...)
+	ctx.add("if err != nil {" + ctx.errReturn + "}")
+	ctx.add("bz = bz[n:]")
+}
+
+// aminoField is a field written by amino, numbered from 1
+type aminoField struct {
+	reflect.StructField
+	num int
+}
+
+func aminoFields(t reflect.Type) []aminoField {
+	fields := make([]aminoField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+			continue
+		}
+		if f.Tag.Get("amino") != "" || f.Tag.Get("binary") != "" {
+			panic(fmt.Sprintf("the amino options of %s.%s are not supported", t, f.Name))
+		}
+		fields = append(fields, aminoField{StructField: f, num: len(fields) + 1})
+	}
+	return fields
+}
+
+// aminoTyp3 is the wire type of t in the keys of the fields
+func aminoTyp3(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Array, reflect.Slice, reflect.String, reflect.Struct:
+		return aminoTyp3ByteLength
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Bool:
+		return aminoTyp3Varint
+	}
+	panic(fmt.Sprintf("%s is not supported by amino", t))
+}
+
+// isUnpackedList tells whether the elements of the list t are written as
+// repeated fields
+func isUnpackedList(t reflect.Type) bool {
+	if (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) || t.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	if t.Elem().Kind() == reflect.Ptr {
+		panic(fmt.Sprintf("the list of pointers %s is not supported", t))
+	}
+	return aminoTyp3(t.Elem()) == aminoTyp3ByteLength
+}
+
+// typeName is the name of t in the generated code
+func (ctx *aminoCtx) typeName(t reflect.Type) string {
+	if alias, ok := ctx.aliases[t]; ok {
+		return alias
+	}
+	if leaf := ctx.leafName(t); leaf != "" {
+		return GetLeafTypes()[t.PkgPath()+"."+t.Name()]
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		return "[]" + ctx.typeName(t.Elem())
+	case reflect.Ptr:
+		return "*" + ctx.typeName(t.Elem())
+	}
+	if t.PkgPath() == "" && t.Name() != "" {
+		return t.Name()
+	}
+	panic(fmt.Sprintf("%s has no name in the generated code", t))
+}
+
+// defaultExpr is the condition on which amino skips a field of t
+func defaultExpr(expr string, t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return expr + " == nil"
+	case reflect.Slice, reflect.String:
+		return "len(" + expr + ") == 0"
+	case reflect.Bool:
+		return "!" + expr
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return expr + " == 0"
+	}
+	// the structs and the arrays are never skipped
+	return ""
+}
+
+// encodeValue writes the value of a field, i.e. the structs, the interfaces
+// and the lists are prefixed by their lengths
+func (ctx *aminoCtx) encodeValue(expr string, t reflect.Type, depth int) {
+	switch leaf := ctx.leafName(t); {
+	case t == timeType:
+		ctx.addCall("aminoEncodeTime(w, %s)", expr)
+		return
+	case leaf != "":
+		ctx.addCall("aminoEncode%s(w, %s)", leaf, expr)
+		return
+	}
+	if alias, ok := ctx.aliases[t]; ok && (t.Kind() == reflect.Struct || t.Kind() == reflect.Interface) {
+		ctx.add("{")
+		ctx.add("start := w.Len()")
+		ctx.addCall("encodeAmino%s(w, %s)", alias, expr)
+		ctx.add("aminoPrefixLength(w, start)")
+		ctx.add("}")
+		return
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		ctx.add("aminoEncodeUvarint(w, uint64(%s))", expr)
+	case reflect.Int8, reflect.Int16:
+		ctx.add("aminoEncodeVarint(w, int64(%s))", expr)
+	case reflect.Bool:
+		ctx.add("aminoEncodeBool(w, bool(%s))", expr)
+	case reflect.String:
+		ctx.add("aminoEncodeString(w, string(%s))", expr)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if t.Kind() == reflect.Array {
+				ctx.add("aminoEncodeBytes(w, %s[:])", expr)
+			} else {
+				ctx.add("aminoEncodeBytes(w, []byte(%s))", expr)
+			}
+			return
+		}
+		ctx.add("{")
+		ctx.add("start := w.Len()")
+		ctx.encodeList(expr, t, 1, depth)
+		ctx.add("aminoPrefixLength(w, start)")
+		ctx.add("}")
+	case reflect.Struct:
+		ctx.add("{")
+		ctx.add("start := w.Len()")
+		ctx.encodeFields(expr, t, depth)
+		ctx.add("aminoPrefixLength(w, start)")
+		ctx.add("}")
+	default:
+		panic(fmt.Sprintf("can not encode %s with amino", t))
+	}
+}
+
+// encodeList writes the elements of a list, packed or as the repeated field num
+func (ctx *aminoCtx) encodeList(expr string, t reflect.Type, num, depth int) {
+	elem := t.Elem()
+	ctx.add("for i%d := range %s {", depth, expr)
+	elemExpr := fmt.Sprintf("%s[i%d]", expr, depth)
+	if !isUnpackedList(t) {
+		ctx.encodeValue(elemExpr, elem, depth+1)
+		ctx.add("}")
+		return
+	}
+	ctx.add("aminoEncodeUvarint(w, %d)", num<<3|aminoTyp3ByteLength)
+	if cond := defaultExpr(elemExpr, elem); cond != "" {
+		ctx.add("if %s {", cond)
+		ctx.add("w.WriteByte(0)")
+		ctx.add("continue")
+		ctx.add("}")
+	}
+	ctx.encodeValue(elemExpr, elem, depth+1)
+	ctx.add("}")
+}
+
+// encodeFields writes the fields of a struct without its length
+func (ctx *aminoCtx) encodeFields(expr string, t reflect.Type, depth int) {
+	for _, f := range aminoFields(t) {
+		fieldExpr, ft := expr+"."+f.Name, f.Type
+		if cond := defaultExpr(fieldExpr, ft); cond != "" {
+			ctx.add("if !(%s) {", cond)
+		} else {
+			ctx.add("{")
+		}
+		switch {
+		case isUnpackedList(ft):
+			ctx.encodeList(fieldExpr, ft, f.num, depth+1)
+		case ft.Kind() == reflect.Ptr:
+			// the pointers are not rolled back
+			ctx.add("aminoEncodeUvarint(w, %d)", f.num<<3|aminoTyp3(ft.Elem()))
+			ctx.encodeValue("(*"+fieldExpr+")", ft.Elem(), depth+1)
+		case ft.Kind() == reflect.Struct:
+			ctx.add("mark := w.Len()")
+			ctx.add("aminoEncodeUvarint(w, %d)", f.num<<3|aminoTyp3(ft))
+			ctx.add("start := w.Len()")
+			ctx.encodeValue(fieldExpr, ft, depth+1)
+			ctx.add("aminoRollback(w, mark, start)")
+		default:
+			ctx.add("aminoEncodeUvarint(w, %d)", f.num<<3|aminoTyp3(ft))
+			ctx.encodeValue(fieldExpr, ft, depth+1)
+		}
+		ctx.add("}")
+	}
+}
+
+// decodeValue reads the value of a field into expr, bz is advanced past it
+func (ctx *aminoCtx) decodeValue(expr string, t reflect.Type, depth int) {
+	ctx.add("{")
+	defer ctx.add("}")
+	switch leaf := ctx.leafName(t); {
+	case t == timeType:
+		ctx.addRead("aminoDecodeTime(bz)")
+		ctx.add("%s = x", expr)
+		return
+	case leaf != "":
+		ctx.addRead("aminoDecode%s(bz)", leaf)
+		ctx.add("%s = x", expr)
+		return
+	}
+	if alias, ok := ctx.aliases[t]; ok && (t.Kind() == reflect.Struct || t.Kind() == reflect.Interface) {
+		ctx.add("buf, n, err := aminoReadLength(bz)")
+		ctx.add("if err != nil {" + ctx.errReturn + "}")
+		ctx.add("bz = bz[n:]")
+		ctx.add("%s, err = decodeAmino%s(buf, depth+1)", expr, alias)
+		ctx.add("if err != nil {" + ctx.errReturn + "}")
+		return
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		kind := map[reflect.Kind]string{reflect.Int: "Int64", reflect.Uint: "Uint64"}[t.Kind()]
+		if kind == "" {
+			kind = strings.Title(t.Kind().String())
+		}
+		ctx.addRead("aminoDecode%s(bz)", kind)
+		ctx.add("%s = %s(x)", expr, ctx.typeName(t))
+	case reflect.Bool:
+		ctx.addRead("aminoDecodeBool(bz)")
+		ctx.add("%s = %s(x)", expr, ctx.typeName(t))
+	case reflect.String:
+		ctx.addRead("aminoDecodeString(bz)")
+		ctx.add("%s = %s(x)", expr, ctx.typeName(t))
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			ctx.addRead("aminoDecodeBytes(bz)")
+			ctx.add("%s = %s(x)", expr, ctx.typeName(t))
+			return
+		}
+		ctx.addRead("aminoReadLength(bz)")
+		if t.Elem().Kind() == reflect.Uint8 {
+			ctx.add("if len(x) != %d {err = ErrArrayLength; %s}", t.Len(), ctx.errReturn)
+			ctx.add("copy(%s[:], x)", expr)
+			return
+		}
+		if t.Kind() == reflect.Array {
+			panic(fmt.Sprintf("the array %s is not supported", t))
+		}
+		ctx.add("bz := x")
+		ctx.decodeList(expr, t, 1, depth)
+		ctx.add("if len(bz) != 0 {err = errAminoLeftOver; %s}", ctx.errReturn)
+	case reflect.Struct:
+		ctx.addRead("aminoReadLength(bz)")
+		ctx.add("bz := x")
+		ctx.decodeFields(expr, t, depth)
+		ctx.add("if len(bz) != 0 {err = errAminoLeftOver; %s}", ctx.errReturn)
+	default:
+		panic(fmt.Sprintf("can not decode %s with amino", t))
+	}
+}
+
+// decodeList reads the elements of a list, packed or as the repeated field num
+func (ctx *aminoCtx) decodeList(expr string, t reflect.Type, num, depth int) {
+	elem := t.Elem()
+	if !isUnpackedList(t) {
+		ctx.add("for len(bz) != 0 {")
+		ctx.add("var e%d %s", depth, ctx.typeName(elem))
+		ctx.decodeValue(fmt.Sprintf("e%d", depth), elem, depth+1)
+		ctx.add("%s = append(%s, e%d)", expr, expr, depth)
+		ctx.add("}")
+		return
+	}
+	ctx.add("for {")
+	ctx.add("ok, n, err := aminoReadKey(bz, %d, aminoTyp3ByteLength)", num)
+	ctx.add("if err != nil {" + ctx.errReturn + "}")
+	ctx.add("if !ok {break}")
+	ctx.add("bz = bz[n:]")
+	ctx.add("var e%d %s", depth, ctx.typeName(elem))
+	// amino writes a zero byte for a default element
+	ctx.add("if len(bz) != 0 && bz[0] == 0 {")
+	ctx.add("bz = bz[1:]")
+	if elem == timeType {
+		ctx.add("e%d = aminoZeroTime", depth)
+	}
+	ctx.add("} else {")
+	ctx.decodeValue(fmt.Sprintf("e%d", depth), elem, depth+1)
+	ctx.add("}")
+	ctx.add("%s = append(%s, e%d)", expr, expr, depth)
+	ctx.add("}")
+}
+
+// decodeFields reads the fields of a struct from bz, which holds no more
+// than the struct
+func (ctx *aminoCtx) decodeFields(expr string, t reflect.Type, depth int) {
+	for _, f := range aminoFields(t) {
+		fieldExpr, ft := expr+"."+f.Name, f.Type
+		if isUnpackedList(ft) {
+			ctx.decodeList(fieldExpr, ft, f.num, depth+1)
+			continue
+		}
+		vt := ft
+		if ft.Kind() == reflect.Ptr {
+			vt = ft.Elem()
+		}
+		ctx.add("{")
+		ctx.add("ok, n, err := aminoReadKey(bz, %d, %d)", f.num, aminoTyp3(vt))
+		ctx.add("if err != nil {" + ctx.errReturn + "}")
+		ctx.add("if ok {")
+		ctx.add("bz = bz[n:]")
+		if ft.Kind() == reflect.Ptr {
+			ctx.add("%s = new(%s)", fieldExpr, ctx.typeName(vt))
+			ctx.decodeValue("(*"+fieldExpr+")", vt, depth+1)
+		} else {
+			ctx.decodeValue(fieldExpr, ft, depth+1)
+		}
+		if ft == timeType {
+			ctx.add("} else {")
+			ctx.add("%s = aminoZeroTime", fieldExpr)
+		} else if vt == timeType {
+			panic(fmt.Sprintf("the pointer %s.%s is not supported", t, f.Name))
+		}
+		ctx.add("}")
+		ctx.add("}")
+	}
+}
+
+// prepareStructFuncs generates encodeAminoX and decodeAminoX, which read and
+// write X without its prefix bytes. For a struct, they are its fields
+// without its length.
+func (ctx *aminoCtx) prepareStructFuncs(alias string) {
+	t := ctx.types[alias]
+	ctx.errReturn = "return err"
+	ctx.add("func encodeAmino%s(w *bytes.Buffer, v %s) error {", alias, alias)
+	if t.Kind() == reflect.Struct && ctx.leafName(t) == "" {
+		ctx.encodeFields("v", t, 0)
+	} else {
+		ctx.encodeValue("v", t, 0)
+	}
+	ctx.add("return nil")
+	ctx.add("} //End of encodeAmino%s", alias)
+	ctx.add("")
+	ctx.errReturn = "return v, err"
+	ctx.add("func decodeAmino%s(bz []byte, depth int) (%s, error) {", alias, alias)
+	ctx.add("var v %s", alias)
+	ctx.add("if err := checkDepth(depth); err != nil {return v, err}")
+	if t.Kind() == reflect.Struct && ctx.leafName(t) == "" {
+		ctx.decodeFields("v", t, 0)
+	} else {
+		ctx.decodeValue("v", t, 0)
+	}
+	ctx.add("if len(bz) != 0 {return v, errAminoLeftOver}")
+	ctx.add("return v, nil")
+	ctx.add("} //End of decodeAmino%s", alias)
+	ctx.add("")
+}
+
+// registeredImpls returns the registered types implementing ifcType, or all
+// the registered types if it is nil. As amino, a type implements an interface
+// if its pointer does.
+func (ctx *aminoCtx) registeredImpls(ifcType reflect.Type) []string {
+	var impls []string
+	for _, alias := range ctx.structs {
+		t := ctx.types[alias]
+		if _, ok := ctx.prefixes[t]; ok && (ifcType == nil || reflect.PtrTo(t).Implements(ifcType)) {
+			impls = append(impls, alias)
+		}
+	}
+	return impls
+}
+
+// writePrefixed writes the prefix bytes of alias and v
+func (ctx *aminoCtx) writePrefixed(alias, expr string) {
+	ctx.add("w.Write(aminoPrefix%s[:])", alias)
+	ctx.add("return encodeAmino%s(w, %s)", alias, expr)
+}
+
+// prepareIfcFuncs generates encodeAminoX and decodeAminoX for an interface,
+// which read and write the prefix bytes and the concrete value. A nil
+// interface is written as nothing.
+func (ctx *aminoCtx) prepareIfcFuncs(alias string) {
+	ifcType := ctx.types[alias]
+	impls := ctx.registeredImpls(ifcType)
+	ctx.add("func encodeAmino%s(w *bytes.Buffer, x %s) error {", alias, alias)
+	ctx.add("switch v := x.(type) {")
+	ctx.add("case nil:")
+	ctx.add("return nil")
+	for _, impl := range impls {
+		if ctx.types[impl].Implements(ifcType) {
+			ctx.add("case %s:", impl)
+			ctx.writePrefixed(impl, "v")
+		}
+		ctx.add("case *%s:", impl)
+		ctx.add("if v == nil {return errAminoNilPointer(v)}")
+		ctx.writePrefixed(impl, "*v")
+	}
+	ctx.add("default:")
+	ctx.add("return errAminoUnregistered(v)")
+	ctx.add("} // end of switch")
+	ctx.add("} // end of encodeAmino%s", alias)
+	ctx.add("")
+	ctx.add("func decodeAmino%s(bz []byte, depth int) (%s, error) {", alias, alias)
+	ctx.add("if err := checkDepth(depth); err != nil {return nil, err}")
+	ctx.add("if len(bz) < 4 {return nil, ErrNotEnoughBytes}")
+	ctx.add("switch [4]byte{bz[0], bz[1], bz[2], bz[3]} {")
+	for _, impl := range impls {
+		ctx.add("case aminoPrefix%s:", impl)
+		ctx.add("v, err := decodeAmino%s(bz[4:], depth+1)", impl)
+		ctx.add("if err != nil {return nil, err}")
+		if ctx.pointers[ctx.types[impl]] {
+			ctx.add("return &v, nil")
+		} else {
+			ctx.add("return v, nil")
+		}
+	}
+	ctx.add("} // end of switch")
+	ctx.add("return nil, ErrUnknownMagicBytes")
+	ctx.add("} // end of decodeAmino%s", alias)
+	ctx.add("")
+}
+
+// prepareAnyFuncs generates EncodeAminoAny and DecodeAminoAny, which read and
+// write as MarshalBinaryBare and UnmarshalBinaryBare of amino
+func (ctx *aminoCtx) prepareAnyFuncs() {
+	ctx.add("func EncodeAminoAny(w *bytes.Buffer, x interface{}) error {")
+	ctx.add("switch v := x.(type) {")
+	for _, alias := range ctx.structs {
+		_, registered := ctx.prefixes[ctx.types[alias]]
+		for _, ptr := range []bool{false, true} {
+			expr := "v"
+			if ptr {
+				ctx.add("case *%s:", alias)
+				ctx.add("if v == nil {return errAminoNilPointer(v)}")
+				expr = "*v"
+			} else {
+				ctx.add("case %s:", alias)
+			}
+			if registered {
+				ctx.writePrefixed(alias, expr)
+			} else {
+				ctx.add("return encodeAmino%s(w, %s)", alias, expr)
+			}
+		}
+	}
+	ctx.add("default:")
+	ctx.add("return errAminoUnregistered(v)")
+	ctx.add("} // end of switch")
+	ctx.add("} // end of EncodeAminoAny")
+	ctx.add("")
+	ctx.add("func DecodeAminoAny(bz []byte) (interface{}, error) {")
+	ctx.add("if len(bz) < 4 {return nil, ErrNotEnoughBytes}")
+	ctx.add("switch [4]byte{bz[0], bz[1], bz[2], bz[3]} {")
+	for _, alias := range ctx.registeredImpls(nil) {
+		ctx.add("case aminoPrefix%s:", alias)
+		ctx.add("return decodeAmino%s(bz[4:], 0)", alias)
+	}
+	ctx.add("} // end of switch")
+	ctx.add("return nil, ErrUnknownMagicBytes")
+	ctx.add("} // end of DecodeAminoAny")
+	ctx.add("")
+}
+
+func (ctx *aminoCtx) preparePrefixVars() {
+	aliases := ctx.registeredImpls(nil)
+	ctx.add("var (")
+	for _, alias := range aliases {
+		prefix := ctx.prefixes[ctx.types[alias]]
+		ctx.add("aminoPrefix%s = [4]byte{%d, %d, %d, %d}", alias, prefix[0], prefix[1], prefix[2], prefix[3])
+	}
+	ctx.add(")")
+	ctx.add("")
+}
+
+// generateAminoFuncs writes EncodeAminoAny and DecodeAminoAny for the types in
+// list, and the functions they use
+func generateAminoFuncs(w io.Writer, list []codon.AliasAndValue, leafTypes map[string]string,
+	registered []RegisteredType) {
+
+	ctx := &aminoCtx{
+		typeTable: newTypeTable(list, leafTypes),
+		prefixes:  make(map[reflect.Type][]byte, len(registered)),
+		pointers:  make(map[reflect.Type]bool),
+	}
+	seen := make(map[string]string, len(registered))
+	for _, rt := range registered {
+		if len(rt.Prefix) != 4 {
+			panic(fmt.Sprintf("the prefix bytes of %s are not 4 bytes", rt.Name))
+		}
+		// amino disambiguates the types with the same prefix bytes
+		if other, ok := seen[string(rt.Prefix)]; ok {
+			panic(fmt.Sprintf("%s and %s have the same prefix bytes", other, rt.Name))
+		}
+		seen[string(rt.Prefix)] = rt.Name
+		t := reflect.TypeOf(rt.Value)
+		ctx.prefixes[t] = rt.Prefix
+		ctx.pointers[t] = rt.Pointer
+	}
+	ctx.preparePrefixVars()
+	for _, alias := range ctx.structs {
+		ctx.prepareStructFuncs(alias)
+	}
+	ifcs := append([]string(nil), ctx.ifcs...)
+	sort.Strings(ifcs)
+	for _, alias := range ifcs {
+		ctx.prepareIfcFuncs(alias)
+	}
+	ctx.prepareAnyFuncs()
+	writeGeneratedLines(w, ctx.lines)
+}
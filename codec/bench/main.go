@@ -2,13 +2,17 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/cosmos/cosmos-sdk/codec"
 
+	"github.com/coinexchain/dex/app"
 	dexcodec "github.com/coinexchain/dex/codec"
 	"github.com/coinexchain/randsrc"
 )
@@ -20,12 +24,8 @@ func main() {
 	}
 	r := randsrc.NewRandSrcFromFile(os.Args[1])
 	accounts := make([]dexcodec.AccountX, 1000)
-	accountsJ := make([][]byte, 1000)
 	for i := 0; i < len(accounts); i++ {
 		accounts[i] = dexcodec.RandAccountX(r)
-		s, _ := json.Marshal(accounts[i])
-		accountsJ[i] = s
-		//fmt.Printf("Here %s\n", s)
 	}
 
 	// Check correctness of codon
@@ -45,9 +45,8 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		s, _ := json.Marshal(v)
-		if !bytes.Equal(s, accountsJ[i]) {
-			fmt.Printf("%s\n%s\n%d mismatch!\n", string(s), string(accountsJ[i]), i)
+		if !dexcodec.EqualAccountX(v, accounts[i]) {
+			fmt.Printf("%d mismatch!\n", i)
 		}
 
 	}
@@ -94,4 +93,99 @@ func main() {
 	}
 	span = time.Now().UnixNano() - nanoSecCount
 	fmt.Printf("Codon: time = %d, bytes = %d, bytes/ns = %f\n", span, totalBytes, float64(totalBytes)/float64(span))
+
+	// the buffers of the round before the previous one are reused, the
+	// decoded accounts refer to the bytes of the previous round
+	bufLists := [2][][]byte{bzList, make([][]byte, len(accounts))}
+	totalBytes = 0
+	nanoSecCount = time.Now().UnixNano()
+	for j := 0; j < 300; j++ {
+		bzList = bufLists[j%2]
+		for i := 0; i < len(accounts); i++ {
+			buf := dexcodec.NewEncodeBuffer(bzList[i])
+			err = buf.BareEncodeAny(accounts[i])
+			if err != nil {
+				panic(err)
+			}
+			bzList[i] = buf.Bytes()
+			totalBytes += len(bzList[i])
+		}
+		for i := 0; i < len(accounts); i++ {
+			_, err = dexcodec.BareDecodeAny(bzList[i], &accounts[i])
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+	span = time.Now().UnixNano() - nanoSecCount
+	fmt.Printf("Codon with EncodeBuffer: time = %d, bytes = %d, bytes/ns = %f\n", span, totalBytes, float64(totalBytes)/float64(span))
+
+	reportAllocs(r)
+}
+
+const allocRuns = 1000
+
+// allocsPerOp returns the average number of allocations of f
+func allocsPerOp(f func()) float64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < allocRuns; i++ {
+		f()
+	}
+	runtime.ReadMemStats(&after)
+	return float64(after.Mallocs-before.Mallocs) / allocRuns
+}
+
+func typeName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	return strings.TrimPrefix(t.PkgPath(), "github.com/") + "." + t.Name()
+}
+
+// reportAllocs prints the allocations per op of encoding a random value of
+// every type by amino, by EncodeAny with a new bytes.Buffer, by a reused
+// EncodeBuffer, and of decoding it by DecodeAny
+func reportAllocs(r dexcodec.RandSrc) {
+	values := make(map[string]interface{})
+	for i := 0; i < 20000; i++ {
+		v := dexcodec.RandAny(r)
+		values[typeName(v)] = v
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cdc := app.MakeCodec()
+	buf := dexcodec.NewEncodeBuffer(make([]byte, 0, 64*1024))
+	fmt.Printf("%-72s %8s %8s %8s %8s\n", "allocs/op", "amino", "encode", "buffer", "decode")
+	for _, name := range names {
+		v := values[name]
+		// amino can not encode some random values, e.g. a time after year 9999
+		amino := "-"
+		if _, err := cdc.MarshalBinaryBare(v); err == nil {
+			amino = fmt.Sprintf("%.1f", allocsPerOp(func() {
+				cdc.MustMarshalBinaryBare(v)
+			}))
+		}
+		encode := allocsPerOp(func() {
+			var b bytes.Buffer
+			if err := dexcodec.EncodeAny(&b, v); err != nil {
+				panic(err)
+			}
+		})
+		buffered := allocsPerOp(func() {
+			buf.Reset()
+			if err := buf.EncodeAny(v); err != nil {
+				panic(err)
+			}
+		})
+		bz := append([]byte(nil), buf.Bytes()...)
+		decode := allocsPerOp(func() {
+			if _, _, err := dexcodec.DecodeAny(bz); err != nil {
+				panic(err)
+			}
+		})
+		fmt.Printf("%-72s %8s %8.1f %8.1f %8.1f\n", name, amino, encode, buffered, decode)
+	}
 }
@@ -0,0 +1,180 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// EncodeBuffer is a byte slice the bufEncode functions append to. Unlike the
+// Encode functions with an io.Writer, they do not allocate when the buffer
+// is large enough, so a buffer should be reused, e.g. by GetEncodeBuffer and
+// PutEncodeBuffer.
+type EncodeBuffer struct {
+	bz []byte
+}
+
+// NewEncodeBuffer returns a buffer appending to bz, which may be pre-sized
+func NewEncodeBuffer(bz []byte) *EncodeBuffer {
+	return &EncodeBuffer{bz: bz[:0]}
+}
+
+// Write makes EncodeBuffer an io.Writer, it never fails
+func (b *EncodeBuffer) Write(p []byte) (int, error) {
+	b.bz = append(b.bz, p...)
+	return len(p), nil
+}
+
+// Bytes returns the encoded bytes, which are valid until the buffer is reset
+func (b *EncodeBuffer) Bytes() []byte {
+	return b.bz
+}
+
+func (b *EncodeBuffer) Len() int {
+	return len(b.bz)
+}
+
+func (b *EncodeBuffer) Reset() {
+	b.bz = b.bz[:0]
+}
+
+// EncodeAny appends the magic bytes and the encoding of x, as EncodeAny
+func (b *EncodeBuffer) EncodeAny(x interface{}) error {
+	return bufEncodeAny(b, x)
+}
+
+// BareEncodeAny appends the encoding of x, as BareEncodeAny
+func (b *EncodeBuffer) BareEncodeAny(x interface{}) error {
+	return bufBareEncodeAny(b, x)
+}
+
+const (
+	encodeBufferSize = 1024
+	// the larger buffers are not pooled, to bound the memory kept by the pool
+	maxPooledBufferSize = 64 * 1024
+)
+
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} {
+		return NewEncodeBuffer(make([]byte, 0, encodeBufferSize))
+	},
+}
+
+// GetEncodeBuffer returns an empty buffer from the pool
+func GetEncodeBuffer() *EncodeBuffer {
+	b := encodeBufferPool.Get().(*EncodeBuffer)
+	b.Reset()
+	return b
+}
+
+// PutEncodeBuffer returns b to the pool, its bytes must not be used after,
+// neither the slices of the values decoded from them
+func PutEncodeBuffer(b *EncodeBuffer) {
+	if cap(b.bz) <= maxPooledBufferSize {
+		encodeBufferPool.Put(b)
+	}
+}
+
+// AppendAny appends the magic bytes and the encoding of x to bz
+func AppendAny(bz []byte, x interface{}) ([]byte, error) {
+	b := EncodeBuffer{bz: bz}
+	err := bufEncodeAny(&b, x)
+	return b.bz, err
+}
+
+// The helpers of the bufEncode functions, they write the same bytes as the
+// codonEncode helpers and the encoders in extraLogics
+
+func bufEncodeBool(w *EncodeBuffer, v bool) error {
+	if v {
+		w.bz = append(w.bz, 1)
+	} else {
+		w.bz = append(w.bz, 0)
+	}
+	return nil
+}
+
+func bufEncodeVarint(w *EncodeBuffer, v int64) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	w.bz = append(w.bz, buf[:n]...)
+	return nil
+}
+
+func bufEncodeUvarint(w *EncodeBuffer, v uint64) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	w.bz = append(w.bz, buf[:n]...)
+	return nil
+}
+
+func bufEncodeInt8(w *EncodeBuffer, v int8) error {
+	w.bz = append(w.bz, byte(v))
+	return nil
+}
+
+func bufEncodeInt16(w *EncodeBuffer, v int16) error {
+	w.bz = append(w.bz, byte(v), byte(v>>8))
+	return nil
+}
+
+func bufEncodeUint8(w *EncodeBuffer, v uint8) error {
+	w.bz = append(w.bz, v)
+	return nil
+}
+
+func bufEncodeUint16(w *EncodeBuffer, v uint16) error {
+	w.bz = append(w.bz, byte(v), byte(v>>8))
+	return nil
+}
+
+func bufEncodeFloat32(w *EncodeBuffer, v float32) error {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], math.Float32bits(v))
+	w.bz = append(w.bz, buf[:]...)
+	return nil
+}
+
+func bufEncodeFloat64(w *EncodeBuffer, v float64) error {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	w.bz = append(w.bz, buf[:]...)
+	return nil
+}
+
+func bufEncodeByteSlice(w *EncodeBuffer, v []byte) error {
+	bufEncodeVarint(w, int64(len(v)))
+	w.bz = append(w.bz, v...)
+	return nil
+}
+
+func bufEncodeString(w *EncodeBuffer, v string) error {
+	bufEncodeVarint(w, int64(len(v)))
+	w.bz = append(w.bz, v...)
+	return nil
+}
+
+func bufEncodeTime(w *EncodeBuffer, t time.Time) error {
+	t = t.UTC()
+	bufEncodeVarint(w, t.Unix())
+	return bufEncodeVarint(w, int64(t.Nanosecond()))
+}
+
+func bufEncodeInt(w *EncodeBuffer, v sdk.Int) error {
+	s, err := v.MarshalAmino()
+	if err != nil {
+		return err
+	}
+	return bufEncodeString(w, s)
+}
+
+func bufEncodeDec(w *EncodeBuffer, v sdk.Dec) error {
+	s, err := v.MarshalAmino()
+	if err != nil {
+		return err
+	}
+	return bufEncodeString(w, s)
+}
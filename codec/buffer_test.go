@@ -0,0 +1,63 @@
+package codec_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinexchain/dex/codec"
+)
+
+func TestEncodeBuffer(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	b := codec.NewEncodeBuffer(nil)
+	for i := 0; i < 10000; i++ {
+		v := codec.RandAny(r)
+		expected := encodeAny(t, v)
+
+		b.Reset()
+		require.Nil(t, b.EncodeAny(v))
+		require.Equal(t, expected, b.Bytes())
+		require.Equal(t, len(expected), b.Len())
+
+		b.Reset()
+		require.Nil(t, b.BareEncodeAny(v))
+		var bare bytes.Buffer
+		require.Nil(t, codec.BareEncodeAny(&bare, v))
+		require.Equal(t, bare.Bytes(), b.Bytes())
+
+		bz, err := codec.AppendAny([]byte{1, 2, 3}, v)
+		require.Nil(t, err)
+		require.Equal(t, append([]byte{1, 2, 3}, expected...), bz)
+	}
+}
+
+func TestEncodeBufferPool(t *testing.T) {
+	b := codec.GetEncodeBuffer()
+	require.Nil(t, b.EncodeAny(codec.RandAny(codec.NewRandSrc(0))))
+	codec.PutEncodeBuffer(b)
+	require.Equal(t, 0, codec.GetEncodeBuffer().Len())
+}
+
+// the values without sdk.Int and sdk.Dec, whose strings are allocated, are
+// encoded into a large enough buffer without any allocation
+func TestEncodeBufferAllocs(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	values := []interface{}{
+		codec.RandMsgSetReferee(r),
+		codec.RandStdSignature(r),
+		codec.RandPubKeyMultisigThreshold(r),
+		codec.RandMsgCancelOrder(r),
+	}
+	b := codec.NewEncodeBuffer(make([]byte, 0, 64*1024))
+	for _, v := range values {
+		allocs := testing.AllocsPerRun(100, func() {
+			b.Reset()
+			if err := b.EncodeAny(v); err != nil {
+				panic(err)
+			}
+		})
+		require.Equal(t, 0.0, allocs, "%T", v)
+	}
+}
@@ -0,0 +1,85 @@
+package codec
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The encoders generated by codon write through io.Writer, every small write
+// of a value goes through an interface call and escapes to the heap, and the
+// magic bytes are looked up by name. resolveMagicBytes replaces the lookups by
+// the variables of the magic bytes, and bufferEncoders copies the Encode
+// functions into bufEncode functions writing to *EncodeBuffer, whose helpers
+// in buffer.go append to a byte slice without allocating.
+
+var (
+	magicCase     = regexp.MustCompile(`case "(\w+)":\s*return \[\]byte\{([\d, ]+)\}`)
+	magicLookup   = regexp.MustCompile(`getMagicBytes\("(\w+)"\)`)
+	encodeFunc    = regexp.MustCompile(`^func (Encode\w+|BareEncodeAny)\(w io\.Writer, `)
+	encodeCall    = regexp.MustCompile(`\b(?:codonEncode|Encode)(\w+)\(w, `)
+	magicFuncName = "func getMagicBytes("
+)
+
+func resolveMagicBytes(src string) string {
+	start := strings.Index(src, magicFuncName)
+	if start < 0 {
+		panic("can not find getMagicBytes")
+	}
+	end := start + strings.Index(src[start:], "\n}\n")
+	magics := make(map[string]string)
+	for _, m := range magicCase.FindAllStringSubmatch(src[start:end], -1) {
+		magics[m[1]] = m[2]
+	}
+	if len(magics) == 0 {
+		panic("can not find the magic bytes in getMagicBytes")
+	}
+
+	names := make([]string, 0, len(magics))
+	for name := range magics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var vars strings.Builder
+	vars.WriteString("\nvar (\n")
+	for _, name := range names {
+		fmt.Fprintf(&vars, "magicBytes%s = [4]byte{%s}\n", name, magics[name])
+	}
+	vars.WriteString(")\n")
+
+	src = magicLookup.ReplaceAllStringFunc(src, func(call string) string {
+		name := magicLookup.FindStringSubmatch(call)[1]
+		if _, ok := magics[name]; !ok {
+			panic("unknown magic bytes of " + name)
+		}
+		return "magicBytes" + name + "[:]"
+	})
+	return src + vars.String()
+}
+
+func bufferEncoders(src string) string {
+	var out strings.Builder
+	count := 0
+	for _, f := range splitFuncs(src) {
+		m := encodeFunc.FindStringSubmatch(f)
+		if m == nil || leafEncoders[m[1]] {
+			continue
+		}
+		count++
+		end := strings.LastIndex(f, "\n}") + 1
+		if i := strings.IndexByte(f[end:], '\n'); i >= 0 {
+			f = f[:end+i]
+		}
+		f = strings.Replace(f, m[0], fmt.Sprintf("func buf%s(w *EncodeBuffer, ", strings.Title(m[1])), 1)
+		f = encodeCall.ReplaceAllString(f, "bufEncode$1(w, ")
+		out.WriteString(f + "\n\n")
+	}
+	if count == 0 {
+		panic("can not find the generated Encode functions")
+	}
+	return out.String()
+}
+
+// the encoders in extraLogics, which are written by hand in buffer.go
+var leafEncoders = map[string]bool{"EncodeTime": true, "EncodeInt": true, "EncodeDec": true}
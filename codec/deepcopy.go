@@ -0,0 +1,206 @@
+package codec
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/coinexchain/codon"
+)
+
+// copyEqualCtx generates the DeepCopyX and EqualX functions, which codon does
+// not support. A struct type is handled field by field, the leaf types by the
+// functions in extraLogics, the other aliased types and the interfaces by
+// their own functions.
+type copyEqualCtx struct {
+	*typeTable
+	lines []string
+}
+
+func (ctx *copyEqualCtx) add(format string, args ...interface{}) {
+	ctx.lines = append(ctx.lines, fmt.Sprintf(format, args...))
+}
+
+// needsCopy tells whether a value of t shares memory with its copy by assignment
+func (ctx *copyEqualCtx) needsCopy(t reflect.Type) bool {
+	if leaf := ctx.leafName(t); leaf != "" {
+		return leaf != "Time"
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Ptr, reflect.Interface, reflect.Map:
+		return true
+	case reflect.Array:
+		return ctx.needsCopy(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if ctx.needsCopy(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ctx *copyEqualCtx) copyValue(expr string, t reflect.Type, depth int, top bool) {
+	if !ctx.needsCopy(t) {
+		return
+	}
+	if leaf := ctx.leafName(t); leaf != "" {
+		ctx.add("%s = DeepCopy%s(%s)", expr, leaf, expr)
+		return
+	}
+	if alias, ok := ctx.aliases[t]; ok && !top {
+		ctx.add("%s = DeepCopy%s(%s)", expr, alias, expr)
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && ctx.needsCopy(f.Type) {
+				panic(fmt.Sprintf("can not copy the unexported field %s of %s", f.Name, t))
+			}
+			ctx.copyValue(expr+"."+f.Name, f.Type, depth, false)
+		}
+	case reflect.Slice:
+		ctx.add("if %s != nil {", expr)
+		ctx.add("%s = append(%s[:0:0], %s...)", expr, expr, expr)
+		if ctx.needsCopy(t.Elem()) {
+			ctx.add("for i%d := range %s {", depth, expr)
+			ctx.copyValue(fmt.Sprintf("%s[i%d]", expr, depth), t.Elem(), depth+1, false)
+			ctx.add("}")
+		}
+		ctx.add("}")
+	case reflect.Array:
+		ctx.add("for i%d := range %s {", depth, expr)
+		ctx.copyValue(fmt.Sprintf("%s[i%d]", expr, depth), t.Elem(), depth+1, false)
+		ctx.add("}")
+	case reflect.Ptr:
+		ctx.add("if %s != nil {", expr)
+		ctx.add("p%d := *%s", depth, expr)
+		ctx.copyValue(fmt.Sprintf("p%d", depth), t.Elem(), depth+1, false)
+		ctx.add("%s = &p%d", expr, depth)
+		ctx.add("}")
+	default:
+		panic(fmt.Sprintf("can not copy %s", t))
+	}
+}
+
+func (ctx *copyEqualCtx) equalValue(a, b string, t reflect.Type, depth int, top bool) {
+	if leaf := ctx.leafName(t); leaf != "" {
+		ctx.add("if !Equal%s(%s, %s) {return false}", leaf, a, b)
+		return
+	}
+	if alias, ok := ctx.aliases[t]; ok && !top {
+		ctx.add("if !Equal%s(%s, %s) {return false}", alias, a, b)
+		return
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		ctx.add("if %s != %s {return false}", a, b)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				panic(fmt.Sprintf("can not compare the unexported field %s of %s", f.Name, t))
+			}
+			ctx.equalValue(a+"."+f.Name, b+"."+f.Name, f.Type, depth, false)
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			ctx.add("if !bytes.Equal(%s, %s) {return false}", a, b)
+			return
+		}
+		ctx.add("if len(%s) != len(%s) {return false}", a, b)
+		ctx.add("for i%d := range %s {", depth, a)
+		ctx.equalValue(fmt.Sprintf("%s[i%d]", a, depth), fmt.Sprintf("%s[i%d]", b, depth), t.Elem(), depth+1, false)
+		ctx.add("}")
+	case reflect.Array:
+		ctx.add("for i%d := range %s {", depth, a)
+		ctx.equalValue(fmt.Sprintf("%s[i%d]", a, depth), fmt.Sprintf("%s[i%d]", b, depth), t.Elem(), depth+1, false)
+		ctx.add("}")
+	case reflect.Ptr:
+		ctx.add("if (%s == nil) != (%s == nil) {return false}", a, b)
+		ctx.add("if %s != nil {", a)
+		ctx.equalValue("(*"+a+")", "(*"+b+")", t.Elem(), depth+1, false)
+		ctx.add("}")
+	default:
+		panic(fmt.Sprintf("can not compare %s", t))
+	}
+}
+
+func (ctx *copyEqualCtx) prepareStructFuncs(alias string) {
+	t := ctx.types[alias]
+	ctx.add("func DeepCopy%s(v %s) %s {", alias, alias, alias)
+	ctx.add("out := v")
+	ctx.copyValue("out", t, 0, true)
+	ctx.add("return out")
+	ctx.add("} //End of DeepCopy%s", alias)
+	ctx.add("")
+	ctx.add("func Equal%s(a, b %s) bool {", alias, alias)
+	ctx.equalValue("a", "b", t, 0, true)
+	ctx.add("return true")
+	ctx.add("} //End of Equal%s", alias)
+	ctx.add("")
+}
+
+// prepareSwitchFuncs generates the functions of an interface, or of any
+// type if ifcType is nil
+func (ctx *copyEqualCtx) prepareSwitchFuncs(name, ifcName string, ifcType reflect.Type) {
+	impls := ctx.implementations(ifcType)
+	ctx.add("func DeepCopy%s(x %s) %s {", name, ifcName, ifcName)
+	ctx.add("switch v := x.(type) {")
+	ctx.add("case nil:")
+	ctx.add("return nil")
+	for _, im := range impls {
+		ctx.add("case %s:", im.typeName())
+		if im.ptr {
+			ctx.add("if v == nil {return v}")
+			ctx.add("c := DeepCopy%s(*v)", im.alias)
+			ctx.add("return &c")
+		} else {
+			ctx.add("return DeepCopy%s(v)", im.alias)
+		}
+	}
+	ctx.add("default:")
+	ctx.add("panic(\"Unknown Type.\")")
+	ctx.add("} // end of switch")
+	ctx.add("} // end of DeepCopy%s", name)
+	ctx.add("")
+
+	ctx.add("func Equal%s(x, y %s) bool {", name, ifcName)
+	ctx.add("switch a := x.(type) {")
+	ctx.add("case nil:")
+	ctx.add("return y == nil")
+	for _, im := range impls {
+		ctx.add("case %s:", im.typeName())
+		ctx.add("b, ok := y.(%s)", im.typeName())
+		if im.ptr {
+			ctx.add("return ok && (a == b || (a != nil && b != nil && Equal%s(*a, *b)))", im.alias)
+		} else {
+			ctx.add("return ok && Equal%s(a, b)", im.alias)
+		}
+	}
+	ctx.add("default:")
+	ctx.add("panic(\"Unknown Type.\")")
+	ctx.add("} // end of switch")
+	ctx.add("} // end of Equal%s", name)
+	ctx.add("")
+}
+
+// generateCopyEqualFuncs writes DeepCopyX and EqualX for every type and
+// interface in list, and DeepCopyAny and EqualAny for all of them
+func generateCopyEqualFuncs(w io.Writer, list []codon.AliasAndValue, leafTypes map[string]string) {
+	ctx := &copyEqualCtx{typeTable: newTypeTable(list, leafTypes)}
+	for _, alias := range ctx.structs {
+		ctx.prepareStructFuncs(alias)
+	}
+	for _, alias := range ctx.ifcs {
+		ctx.prepareSwitchFuncs(alias, alias, ctx.types[alias])
+	}
+	ctx.prepareSwitchFuncs("Any", "interface{}", nil)
+	writeGeneratedLines(w, ctx.lines)
+}
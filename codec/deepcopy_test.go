@@ -0,0 +1,124 @@
+package codec_test
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinexchain/dex/codec"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// requireNoSharedMemory checks that a and its copy b share no slice or pointer
+func requireNoSharedMemory(t *testing.T, a, b reflect.Value) {
+	switch a.Kind() {
+	case reflect.Ptr:
+		if !a.IsNil() {
+			require.NotEqual(t, a.Pointer(), b.Pointer(), a.Type().String())
+			requireNoSharedMemory(t, a.Elem(), b.Elem())
+		}
+	case reflect.Slice:
+		if a.Len() > 0 {
+			require.NotEqual(t, a.Pointer(), b.Pointer(), a.Type().String())
+		}
+		for i := 0; i < a.Len(); i++ {
+			requireNoSharedMemory(t, a.Index(i), b.Index(i))
+		}
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			requireNoSharedMemory(t, a.Index(i), b.Index(i))
+		}
+	case reflect.Interface:
+		if !a.IsNil() {
+			requireNoSharedMemory(t, a.Elem(), b.Elem())
+		}
+	case reflect.Struct:
+		// the location of a time is immutable
+		if a.Type() == timeType {
+			return
+		}
+		for i := 0; i < a.NumField(); i++ {
+			requireNoSharedMemory(t, a.Field(i), b.Field(i))
+		}
+	}
+}
+
+func encodeAny(t *testing.T, v interface{}) []byte {
+	var buf bytes.Buffer
+	require.Nil(t, codec.EncodeAny(&buf, v))
+	return buf.Bytes()
+}
+
+func TestDeepCopyAny(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	for i := 0; i < 10000; i++ {
+		v := codec.RandAny(r)
+		c := codec.DeepCopyAny(v)
+		require.True(t, codec.EqualAny(v, c))
+		require.Equal(t, encodeAny(t, v), encodeAny(t, c))
+		requireNoSharedMemory(t, reflect.ValueOf(v), reflect.ValueOf(c))
+
+		// a pointer is only equal to a pointer
+		ptr := reflect.New(reflect.TypeOf(v))
+		ptr.Elem().Set(reflect.ValueOf(v))
+		require.False(t, codec.EqualAny(v, ptr.Interface()))
+		ptrCopy := codec.DeepCopyAny(ptr.Interface())
+		require.True(t, codec.EqualAny(ptr.Interface(), ptrCopy))
+		requireNoSharedMemory(t, ptr, reflect.ValueOf(ptrCopy))
+	}
+	require.Nil(t, codec.DeepCopyAny(nil))
+	require.True(t, codec.EqualAny(nil, nil))
+	require.False(t, codec.EqualAny(nil, codec.RandAny(r)))
+}
+
+func TestDeepCopyInterfaces(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	for i := 0; i < 1000; i++ {
+		pubKey := codec.RandPubKey(r)
+		require.True(t, codec.EqualPubKey(pubKey, codec.DeepCopyPubKey(pubKey)))
+		msg := codec.RandMsg(r)
+		require.True(t, codec.EqualMsg(msg, codec.DeepCopyMsg(msg)))
+		account := codec.RandAccount(r)
+		require.True(t, codec.EqualAccount(account, codec.DeepCopyAccount(account)))
+		content := codec.RandContent(r)
+		require.True(t, codec.EqualContent(content, codec.DeepCopyContent(content)))
+
+		require.False(t, codec.EqualMsg(msg, codec.RandMsg(r)))
+	}
+}
+
+// TestEqualAny checks that two values are equal iff their encodings are the
+// same, the values are made different by changing one byte of an encoding
+func TestEqualAny(t *testing.T) {
+	r := codec.NewRandSrc(1)
+	rnd := rand.New(rand.NewSource(1))
+	equal, different := 0, 0
+	for i := 0; i < 20000; i++ {
+		v := codec.RandAny(r)
+		bz := encodeAny(t, v)
+		if len(bz) == 4 {
+			continue
+		}
+		changed := append([]byte(nil), bz...)
+		j := 4 + rnd.Intn(len(bz)-4)
+		changed[j] = byte(rnd.Intn(256))
+		w, n, err := codec.DecodeAny(changed)
+		if err != nil || n != len(changed) {
+			continue
+		}
+		sameBytes := bytes.Equal(bz, encodeAny(t, w))
+		require.Equal(t, sameBytes, codec.EqualAny(v, w), "%x", changed)
+		require.Equal(t, sameBytes, codec.EqualAny(w, v), "%x", changed)
+		if sameBytes {
+			equal++
+		} else {
+			different++
+		}
+	}
+	require.True(t, equal > 0 && different > 0)
+}
@@ -0,0 +1,241 @@
+//go:build go1.18
+// +build go1.18
+
+// the native fuzzing needs go1.18, the other tests of the package do not
+
+package codec_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/dex/codec"
+)
+
+// the number of values generated by the Rand* functions as the seed corpus
+const seedCount = 200
+
+// a decoder must not allocate much more than the size of its input
+const (
+	maxAllocPerByte = 256
+	maxAllocBase    = 1 << 20
+)
+
+type encodeFunc func(w io.Writer, v interface{}) error
+
+// aminoDecodeFunc unmarshals the same input as the codon decoder of a fuzz
+// test with amino
+type aminoDecodeFunc func(bz []byte) (interface{}, error)
+
+// aminoDecoder returns an aminoDecodeFunc which unmarshals into the first of
+// the targets accepting the input, a target is a pointer to an interface or a
+// concrete type
+func aminoDecoder(newTargets ...func() interface{}) aminoDecodeFunc {
+	return func(bz []byte) (v interface{}, err error) {
+		defer func() {
+			// the robustness of amino itself is not tested here
+			if r := recover(); r != nil {
+				v, err = nil, fmt.Errorf("amino panics: %v", r)
+			}
+		}()
+		for _, newTarget := range newTargets {
+			ptr := newTarget()
+			if err = aminoCdc.UnmarshalBinaryBare(bz, ptr); err != nil {
+				continue
+			}
+			if v = reflect.ValueOf(ptr).Elem().Interface(); v == nil {
+				return nil, fmt.Errorf("amino decodes a nil %T", ptr)
+			}
+			return v, nil
+		}
+		return nil, err
+	}
+}
+
+// addSeeds adds the codon and the amino encodings of the generated values
+func addSeeds(f *testing.F, gen func(r codec.RandSrc) interface{}, encode encodeFunc) {
+	r := codec.NewRandSrc(0)
+	for i := 0; i < seedCount; i++ {
+		v := gen(r)
+		var buf bytes.Buffer
+		require.Nil(f, encode(&buf, v))
+		f.Add(buf.Bytes())
+		if aminoBytes, err := aminoCdc.MarshalBinaryBare(v); err == nil {
+			f.Add(aminoBytes)
+		}
+	}
+}
+
+// checkDecode decodes the input with both codecs. The codon decoder must never
+// panic or over-allocate, and anything it accepts must re-encode identically.
+// As the wire formats differ, the codecs can not accept the same bytes, so
+// they are compared on the values: a value accepted by either codec must be
+// accepted by the other one from its own encoding, and decode to the same.
+func checkDecode(t *testing.T, bz []byte, decode decodeFunc, encode encodeFunc, aminoDecode aminoDecodeFunc) {
+	v, n, err, allocated := decodeSafely(t, decode, bz)
+	limit := maxAllocBase + maxAllocPerByte*uint64(len(bz))
+	require.True(t, allocated <= limit, "%d bytes allocated to decode %d bytes", allocated, len(bz))
+	if err == nil {
+		require.True(t, n <= len(bz))
+		var buf bytes.Buffer
+		require.Nil(t, encode(&buf, v))
+		require.Equal(t, bz[:n], buf.Bytes())
+		checkAmino(t, v, encode)
+	}
+
+	if v, err := aminoDecode(bz); err == nil {
+		checkCodon(t, v, decode, encode)
+	}
+}
+
+// checkAmino checks that amino accepts the amino encoding of a value accepted
+// by codon, and decodes it to the same value
+func checkAmino(t *testing.T, v interface{}, encode encodeFunc) {
+	aminoBytes, err := aminoCdc.MarshalBinaryBare(v)
+	if err != nil {
+		// out of the range of amino, e.g. a time before year 1
+		return
+	}
+	ptr := reflect.New(reflect.TypeOf(v))
+	require.Nil(t, aminoCdc.UnmarshalBinaryBare(aminoBytes, ptr.Interface()), "amino rejects %x", aminoBytes)
+	require.Equal(t, aminoBytes, aminoCdc.MustMarshalBinaryBare(ptr.Elem().Interface()))
+	requireSameCodon(t, v, ptr.Elem().Interface(), encode)
+}
+
+// checkCodon checks that codon accepts the codon encoding of a value accepted
+// by amino, and decodes it to the same value
+func checkCodon(t *testing.T, v interface{}, decode decodeFunc, encode encodeFunc) {
+	if hasNilPointer(reflect.ValueOf(v)) {
+		// codon has no encoding for nil pointers, e.g. the BaseVestingAccount
+		// of a vesting account, which are never written by the app
+		return
+	}
+	var buf bytes.Buffer
+	require.Nil(t, encode(&buf, v), "codon can not encode %T accepted by amino", v)
+	decoded, n, err, _ := decodeSafely(t, decode, buf.Bytes())
+	require.Nil(t, err, "codon rejects %x", buf.Bytes())
+	require.Equal(t, buf.Len(), n)
+	require.Equal(t, aminoCdc.MustMarshalBinaryBare(v), aminoCdc.MustMarshalBinaryBare(decoded))
+	requireSameCodon(t, v, decoded, encode)
+}
+
+var leafTypes = map[reflect.Type]bool{
+	reflect.TypeOf(sdk.Int{}):   true,
+	reflect.TypeOf(sdk.Dec{}):   true,
+	reflect.TypeOf(time.Time{}): true,
+}
+
+func hasNilPointer(v reflect.Value) bool {
+	if leafTypes[v.Type()] {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		return v.IsNil() || hasNilPointer(v.Elem())
+	case reflect.Interface:
+		return !v.IsNil() && hasNilPointer(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if hasNilPointer(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if hasNilPointer(v.Index(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func requireSameCodon(t *testing.T, expected, actual interface{}, encode encodeFunc) {
+	var expectedBuf, actualBuf bytes.Buffer
+	require.Nil(t, encode(&expectedBuf, expected))
+	require.Nil(t, encode(&actualBuf, actual))
+	require.Equal(t, expectedBuf.Bytes(), actualBuf.Bytes())
+}
+
+// the amino targets of the interfaces
+func newMsg() interface{}     { return new(codec.Msg) }
+func newAccount() interface{} { return new(codec.Account) }
+func newTx() interface{}      { return new(sdk.Tx) }
+func newPubKey() interface{}  { return new(codec.PubKey) }
+func newContent() interface{} { return new(codec.Content) }
+
+func FuzzDecodeAny(f *testing.F) {
+	addSeeds(f, codec.RandAny, codec.EncodeAny)
+	aminoDecode := aminoDecoder(newMsg, newAccount, newTx, newPubKey, newContent)
+	f.Fuzz(func(t *testing.T, bz []byte) {
+		checkDecode(t, bz, codec.DecodeAny, codec.EncodeAny, aminoDecode)
+	})
+}
+
+func FuzzDecodeMsg(f *testing.F) {
+	gen := func(r codec.RandSrc) interface{} { return codec.RandMsg(r) }
+	addSeeds(f, gen, codec.EncodeMsg)
+	decode := func(bz []byte) (interface{}, int, error) { return codec.DecodeMsg(bz) }
+	f.Fuzz(func(t *testing.T, bz []byte) {
+		checkDecode(t, bz, decode, codec.EncodeMsg, aminoDecoder(newMsg))
+	})
+}
+
+func FuzzDecodeAccount(f *testing.F) {
+	gen := func(r codec.RandSrc) interface{} { return codec.RandAccount(r) }
+	addSeeds(f, gen, codec.EncodeAccount)
+	decode := func(bz []byte) (interface{}, int, error) { return codec.DecodeAccount(bz) }
+	f.Fuzz(func(t *testing.T, bz []byte) {
+		checkDecode(t, bz, decode, codec.EncodeAccount, aminoDecoder(newAccount))
+	})
+}
+
+func FuzzDecodeStdTx(f *testing.F) {
+	gen := func(r codec.RandSrc) interface{} { return codec.RandStdTx(r) }
+	encode := func(w io.Writer, v interface{}) error { return codec.EncodeStdTx(w, v.(codec.StdTx)) }
+	addSeeds(f, gen, encode)
+	decode := func(bz []byte) (interface{}, int, error) { return codec.DecodeStdTx(bz) }
+	f.Fuzz(func(t *testing.T, bz []byte) {
+		checkDecode(t, bz, decode, encode, aminoDecoder(func() interface{} { return new(codec.StdTx) }))
+	})
+}
+
+// FuzzTxDecoder requires the tx decoder to return the same txs and errors as
+// the amino decoder, for the length-prefixed amino txs and their mutations
+func FuzzTxDecoder(f *testing.F) {
+	r := codec.NewRandSrc(0)
+	for i := 0; i < seedCount; i++ {
+		if txBytes, err := aminoCdc.MarshalBinaryLengthPrefixed(codec.RandStdTx(r)); err == nil {
+			f.Add(txBytes)
+		}
+	}
+	aminoTxDecoder := func(txBytes []byte) (tx sdk.Tx, err sdk.Error) {
+		defer func() {
+			// the robustness of amino itself is not tested here
+			if r := recover(); r != nil {
+				tx, err = nil, sdk.ErrTxDecode(fmt.Sprintf("amino panics: %v", r))
+			}
+		}()
+		return auth.DefaultTxDecoder(aminoCdc)(txBytes)
+	}
+	txDecoder := codec.NewTxDecoder(aminoTxDecoder)
+	f.Fuzz(func(t *testing.T, txBytes []byte) {
+		expected, expectedErr := aminoTxDecoder(txBytes)
+		tx, err := txDecoder(txBytes)
+		require.Equal(t, expected, tx)
+		if expectedErr == nil {
+			require.Nil(t, err)
+		} else {
+			require.Equal(t, expectedErr.Error(), err.Error())
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package codec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The decoders generated by codon trust their inputs: they allocate slices of
+// any length read from the input, index past its end and panic on unknown
+// magic bytes. hardenDecoders rewrites them to return errors instead, to
+// enforce the DecodeLimits and to bound the nesting depth. Each Decode function
+// becomes a wrapper of a decode function with a depth argument, which is
+// increased by one for every nested Decode call.
+
+var (
+	funcStart     = regexp.MustCompile(`(?m)^func `)
+	decodeFunc    = regexp.MustCompile(`^func Decode(\w+)\(bz \[\]byte\) \((.+), int, error\) \{\n`)
+	decodeCall    = regexp.MustCompile(`\bDecode(\w+)\((bz(?:\[4:\])?)\)`)
+	sliceMake     = regexp.MustCompile(`(?m)^(\S+ = make\(\[\]\w+, length\))$`)
+	arrayLoop     = regexp.MustCompile(`(?m)^(for _0, length_0 := 0, length; _0<length_0; _0\+\+ \{ //array of \w+\n)(\S+)\[_0\]`)
+	lengthDecode  = "length = codonDecodeInt(bz, &n, &err)"
+	magicBytes    = "for i:=0; i<4; i++ {magicBytes[i] = bz[i]}"
+	unknownMagic  = `panic("Unknown type")`
+	errorsImport  = "\n\"errors\"\n"
+	headerDecoder = "func codonDecodeBool("
+)
+
+func hardenDecoders(src string) string {
+	// the decoding helpers of codon are replaced by the ones in limits.go
+	start := strings.Index(src, headerDecoder)
+	end := strings.Index(src, extraLogics)
+	if start < 0 || end < start {
+		panic("can not find the decoding helpers generated by codon")
+	}
+	src = src[:start] + src[end:]
+	if !strings.Contains(src, "errors.") {
+		src = strings.Replace(src, errorsImport, "\n", 1)
+	}
+	src = strings.Replace(src, lengthDecode, "length = codonDecodeLength(bz, &n, &err)", -1)
+
+	funcs := splitFuncs(src)
+	names := make(map[string]bool)
+	for _, f := range funcs {
+		if m := decodeFunc.FindStringSubmatch(f); m != nil {
+			names[m[1]] = true
+		}
+	}
+	if len(names) == 0 {
+		panic("can not find the generated Decode functions")
+	}
+
+	var out strings.Builder
+	for _, f := range funcs {
+		if m := decodeFunc.FindStringSubmatch(f); m != nil {
+			f = hardenDecodeFunc(f, m[1], m[2], names)
+		}
+		out.WriteString(f)
+	}
+	return out.String()
+}
+
+func splitFuncs(src string) []string {
+	starts := funcStart.FindAllStringIndex(src, -1)
+	funcs := make([]string, 0, len(starts)+1)
+	prev := 0
+	for _, loc := range starts {
+		funcs = append(funcs, src[prev:loc[0]])
+		prev = loc[0]
+	}
+	return append(funcs, src[prev:])
+}
+
+func hardenDecodeFunc(f, name, typ string, names map[string]bool) string {
+	body := strings.TrimPrefix(f, decodeFunc.FindString(f))
+
+	body = sliceMake.ReplaceAllString(body,
+		"err = checkSliceLength(length)\nif err != nil {return v, total, err}\n$1")
+
+	if n := strings.Count(body, "//array of"); n != len(arrayLoop.FindAllString(body, -1)) {
+		panic("unexpected array decoding in Decode" + name)
+	}
+	body = arrayLoop.ReplaceAllString(body,
+		"if length != len($2) {return v, total, ErrArrayLength}\n$1$2[_0]")
+
+	if strings.Contains(body, magicBytes) {
+		body = strings.Replace(body, magicBytes,
+			"if len(bz) < 4 {return v, n, ErrNotEnoughBytes}\n"+magicBytes, 1)
+		body = strings.Replace(body, unknownMagic, "return v, n, ErrUnknownMagicBytes", 1)
+	}
+
+	body = decodeCall.ReplaceAllStringFunc(body, func(call string) string {
+		m := decodeCall.FindStringSubmatch(call)
+		if !names[m[1]] {
+			return call
+		}
+		return fmt.Sprintf("decode%s(%s, depth+1)", m[1], m[2])
+	})
+
+	return fmt.Sprintf("func Decode%s(bz []byte) (%s, int, error) {\nreturn decode%s(bz, 0)\n}\n\n", name, typ, name) +
+		fmt.Sprintf("func decode%s(bz []byte, depth int) (%s, int, error) {\n", name, typ) +
+		fmt.Sprintf("if err := checkDepth(depth); err != nil {\nvar v %s\nreturn v, 0, err\n}\n", typ) +
+		body
+}
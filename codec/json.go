@@ -0,0 +1,142 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The helpers of the generated JSON encoders, which write the same bytes as
+// the JSON encoding of amino, or as encoding/json.
+
+// jsonSafe tells whether an ASCII byte is written as is by encoding/json,
+// which escapes the control characters, '"', '\\' and the HTML characters
+var jsonSafe [128]bool
+
+func init() {
+	for b := 0x20; b < len(jsonSafe); b++ {
+		jsonSafe[b] = true
+	}
+	for _, b := range []byte{'"', '\\', '<', '>', '&'} {
+		jsonSafe[b] = false
+	}
+}
+
+func jsonWriteString(w *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 || !jsonSafe[s[i]] {
+			// the escaping and the non-ASCII characters are left to encoding/json,
+			// whose output changes among the versions of Go
+			bz, _ := json.Marshal(s)
+			w.Write(bz)
+			return
+		}
+	}
+	w.WriteByte('"')
+	w.WriteString(s)
+	w.WriteByte('"')
+}
+
+func jsonWriteBytes(w *bytes.Buffer, bz []byte) {
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(bz)))
+	base64.StdEncoding.Encode(buf, bz)
+	w.WriteByte('"')
+	w.Write(buf)
+	w.WriteByte('"')
+}
+
+// amino writes int64 and uint64 in strings, since JS can not handle them
+
+func jsonWriteQuotedInt(w *bytes.Buffer, i int64) {
+	w.WriteByte('"')
+	w.WriteString(strconv.FormatInt(i, 10))
+	w.WriteByte('"')
+}
+
+func jsonWriteQuotedUint(w *bytes.Buffer, u uint64) {
+	w.WriteByte('"')
+	w.WriteString(strconv.FormatUint(u, 10))
+	w.WriteByte('"')
+}
+
+func jsonWriteInt(w *bytes.Buffer, i int64) {
+	w.WriteString(strconv.FormatInt(i, 10))
+}
+
+func jsonWriteUint(w *bytes.Buffer, u uint64) {
+	w.WriteString(strconv.FormatUint(u, 10))
+}
+
+func jsonWriteBool(w *bytes.Buffer, b bool) {
+	if b {
+		w.WriteString("true")
+	} else {
+		w.WriteString("false")
+	}
+}
+
+// The writers of the frequent types implementing json.Marshaler, which are
+// used instead of their MarshalJSON methods
+
+func jsonWriteSdkInt(w *bytes.Buffer, i sdk.Int) {
+	if i == (sdk.Int{}) {
+		w.WriteString(`"0"`)
+		return
+	}
+	w.WriteByte('"')
+	w.WriteString(i.String())
+	w.WriteByte('"')
+}
+
+func jsonWriteCoins(w *bytes.Buffer, coins sdk.Coins) {
+	w.WriteByte('[')
+	for i, coin := range coins {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(`{"denom":`)
+		jsonWriteString(w, coin.Denom)
+		w.WriteString(`,"amount":`)
+		jsonWriteSdkInt(w, coin.Amount)
+		w.WriteByte('}')
+	}
+	w.WriteByte(']')
+}
+
+func jsonWriteAccAddress(w *bytes.Buffer, addr sdk.AccAddress) {
+	jsonWriteString(w, addr.String())
+}
+
+// jsonWriteMarshaler writes the output of MarshalJSON as is, like amino
+func jsonWriteMarshaler(w *bytes.Buffer, m json.Marshaler) error {
+	bz, err := m.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	w.Write(bz)
+	return nil
+}
+
+// jsonWriteGoJSON writes v as encoding/json does, for the types implementing
+// json.Marshaler or encoding.TextMarshaler, whose outputs are compacted and
+// escaped by encoding/json
+func jsonWriteGoJSON(w *bytes.Buffer, v interface{}) error {
+	bz, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Write(bz)
+	return nil
+}
+
+func errJSONUnregistered(v interface{}) error {
+	return fmt.Errorf("can not encode unregistered concrete type %T", v)
+}
+
+func errJSONNilPointer(v interface{}) error {
+	return fmt.Errorf("illegal nil-pointer of type %T for registered interface", v)
+}
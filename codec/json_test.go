@@ -0,0 +1,84 @@
+package codec_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinexchain/dex/app"
+	"github.com/coinexchain/dex/codec"
+)
+
+var aminoCdc = app.MakeCodec()
+
+var interfaceNames = map[string]bool{
+	"github.com/cosmos/cosmos-sdk/types.Msg":               true,
+	"github.com/cosmos/cosmos-sdk/x/auth/exported.Account": true,
+	"github.com/cosmos/cosmos-sdk/x/gov/types.Content":     true,
+	"github.com/tendermint/tendermint/crypto.PubKey":       true,
+}
+
+// requireSameJSON returns false if amino can not encode v
+func requireSameJSON(t *testing.T, v interface{}) bool {
+	aminoJSON, aminoErr := aminoCdc.MarshalJSON(v)
+	codonJSON, err := codec.MarshalJSONAny(v)
+	if aminoErr != nil {
+		// e.g. a time after year 9999
+		require.NotNil(t, err, "%T", v)
+		return false
+	}
+	require.Nil(t, err, "%T", v)
+	require.Equal(t, string(aminoJSON), string(codonJSON))
+	return true
+}
+
+func TestMarshalJSONAny(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	compared := make(map[string]bool)
+	for i := 0; i < 20000; i++ {
+		v := codec.RandAny(r)
+		if requireSameJSON(t, v) {
+			rt := reflect.TypeOf(v)
+			compared[rt.PkgPath()+"."+rt.Name()] = true
+		}
+
+		ptr := reflect.New(reflect.TypeOf(v))
+		ptr.Elem().Set(reflect.ValueOf(v))
+		requireSameJSON(t, ptr.Interface())
+		requireSameJSON(t, reflect.Zero(ptr.Type()).Interface())
+	}
+	requireSameJSON(t, nil)
+	for _, name := range codec.GetSupportList() {
+		require.True(t, compared[name] || interfaceNames[name], name)
+	}
+}
+
+func TestMarshalJSONStrings(t *testing.T) {
+	for _, s := range []string{"", "cet", "<a href=\"x\">&amp;</a>", "tab\tnew\nline\\", "\x00\x1f\x7f",
+		"中文", "  ", "\xff\xfe"} {
+		requireSameJSON(t, codec.TextProposal{Title: s, Description: s})
+	}
+}
+
+func TestMarshalGoJSONStdTx(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	for i := 0; i < 5000; i++ {
+		tx := codec.RandStdTx(r)
+		goJSON, goErr := json.Marshal(&tx)
+		codonJSON, err := codec.MarshalGoJSONStdTx(tx)
+		if goErr != nil {
+			// e.g. a time after year 9999
+			require.NotNil(t, err)
+			continue
+		}
+		require.Nil(t, err)
+		require.Equal(t, string(goJSON), string(codonJSON))
+	}
+	goJSON, err := json.Marshal(&codec.StdTx{})
+	require.Nil(t, err)
+	codonJSON, err := codec.MarshalGoJSONStdTx(codec.StdTx{})
+	require.Nil(t, err)
+	require.Equal(t, string(goJSON), string(codonJSON))
+}
@@ -0,0 +1,462 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/codon"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+
+	// the writers in json.go of some types implementing json.Marshaler
+	jsonWriters = map[reflect.Type]string{
+		reflect.TypeOf(sdk.Int{}):        "jsonWriteSdkInt",
+		reflect.TypeOf(sdk.Coins{}):      "jsonWriteCoins",
+		reflect.TypeOf(sdk.AccAddress{}): "jsonWriteAccAddress",
+	}
+)
+
+// jsonCtx generates the JSON encoders, which follow the rules of amino JSON:
+// the registered types are wrapped by {"type":...,"value":...} in interfaces
+// and at the top level, the int64 and uint64 values are written as strings
+// and the byte slices in base64. With goJSON, they follow the rules of
+// encoding/json instead: no wrappers, the integers are written as numbers and
+// the byte arrays as arrays of numbers.
+type jsonCtx struct {
+	*typeTable
+	names  map[reflect.Type]string
+	goJSON bool
+	lines  []string
+	// the statement returning err in the function being generated
+	errReturn string
+}
+
+// funcName is the name of the generated function of kind, e.g. "encode" or
+// "Marshal", for alias
+func (ctx *jsonCtx) funcName(kind, alias string) string {
+	if ctx.goJSON {
+		return kind + "GoJSON" + alias
+	}
+	return kind + "JSON" + alias
+}
+
+func (ctx *jsonCtx) add(format string, args ...interface{}) {
+	ctx.lines = append(ctx.lines, fmt.Sprintf(format, args...))
+}
+
+func (ctx *jsonCtx) addCall(format string, args ...interface{}) {
+	ctx.add("if err := "+format+"; err != nil {"+ctx.errReturn+"}", args...)
+}
+
+// jsonField is a field written by amino
+type jsonField struct {
+	reflect.StructField
+	// the selector of the field, Outer.Inner for a promoted one
+	path      string
+	name      string
+	omitEmpty bool
+}
+
+func jsonFields(t reflect.Type) []jsonField {
+	fields := make([]jsonField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = f.Name
+		}
+		omitEmpty := false
+		for _, opt := range parts[1:] {
+			omitEmpty = omitEmpty || opt == "omitempty"
+			if opt == "string" {
+				panic(fmt.Sprintf("the string option of %s.%s is not supported", t, f.Name))
+			}
+		}
+		fields = append(fields, jsonField{StructField: f, path: f.Name, name: name, omitEmpty: omitEmpty})
+	}
+	return fields
+}
+
+// goJSONFields returns the fields written by encoding/json, which promotes
+// the fields of the untagged embedded structs
+func goJSONFields(t reflect.Type) []jsonField {
+	fields := make([]jsonField, 0, t.NumField())
+	names := make(map[string]bool)
+	for _, f := range jsonFields(t) {
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() != reflect.Interface {
+			if f.Type.Kind() != reflect.Struct {
+				panic(fmt.Sprintf("the embedded %s.%s is not supported", t, f.Name))
+			}
+			for _, inner := range goJSONFields(f.Type) {
+				inner.path = f.Name + "." + inner.path
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		fields = append(fields, f)
+	}
+	for _, f := range fields {
+		if names[f.name] {
+			// encoding/json drops or hides the conflicted fields
+			panic(fmt.Sprintf("the JSON name %s of %s is conflicted", f.name, t))
+		}
+		names[f.name] = true
+	}
+	return fields
+}
+
+// omitEmpty tells whether the field may be omitted, encoding/json never omits
+// a struct
+func (ctx *jsonCtx) omitEmpty(f jsonField) bool {
+	return f.omitEmpty && !(ctx.goJSON && f.Type.Kind() == reflect.Struct)
+}
+
+// emptyExpr is the condition on which amino omits a field with omitempty
+func (ctx *jsonCtx) emptyExpr(expr string, t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return expr + " == nil"
+	case reflect.Slice, reflect.String, reflect.Array:
+		return "len(" + expr + ") == 0"
+	case reflect.Bool:
+		return "!" + expr
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return expr + " == 0"
+	case reflect.Struct:
+		if leaf := ctx.leafName(t); leaf != "" && t.Comparable() {
+			return fmt.Sprintf("%s == (%s{})", expr, GetLeafTypes()[t.PkgPath()+"."+t.Name()])
+		}
+		if alias, ok := ctx.aliases[t]; ok && t.Comparable() {
+			return fmt.Sprintf("%s == (%s{})", expr, alias)
+		}
+	}
+	panic(fmt.Sprintf("can not omit an empty %s", t))
+}
+
+func (ctx *jsonCtx) encodeValue(expr string, t reflect.Type, depth int, top bool) {
+	if t == timeType && !ctx.goJSON {
+		// amino strips the timezone
+		ctx.addCall("jsonWriteMarshaler(w, %s.Round(0).UTC())", expr)
+		return
+	}
+	if writer, ok := jsonWriters[t]; ok {
+		ctx.add("%s(w, %s)", writer, expr)
+		return
+	}
+	if t.Implements(jsonMarshalerType) || (ctx.goJSON && t.Implements(textMarshalerType)) {
+		if ctx.goJSON {
+			// encoding/json compacts the output and escapes the HTML characters
+			ctx.addCall("jsonWriteGoJSON(w, %s)", expr)
+		} else {
+			ctx.addCall("jsonWriteMarshaler(w, %s)", expr)
+		}
+		return
+	}
+	if reflect.PtrTo(t).Implements(jsonMarshalerType) || (ctx.goJSON && reflect.PtrTo(t).Implements(textMarshalerType)) {
+		// amino and encoding/json call it only on the addressable values
+		panic(fmt.Sprintf("%s implements json.Marshaler by pointer", t))
+	}
+	if _, ok := reflect.PtrTo(t).MethodByName("MarshalAmino"); ok && !ctx.goJSON {
+		panic(fmt.Sprintf("%s implements MarshalAmino", t))
+	}
+	if alias, ok := ctx.aliases[t]; ok && !top {
+		ctx.addCall("%s(w, %s)", ctx.funcName("encode", alias), expr)
+		return
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		if ctx.goJSON {
+			ctx.add("jsonWriteInt(w, int64(%s))", expr)
+		} else {
+			ctx.add("jsonWriteQuotedInt(w, int64(%s))", expr)
+		}
+	case reflect.Uint, reflect.Uint64:
+		if ctx.goJSON {
+			ctx.add("jsonWriteUint(w, uint64(%s))", expr)
+		} else {
+			ctx.add("jsonWriteQuotedUint(w, uint64(%s))", expr)
+		}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		ctx.add("jsonWriteInt(w, int64(%s))", expr)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		ctx.add("jsonWriteUint(w, uint64(%s))", expr)
+	case reflect.Bool:
+		ctx.add("jsonWriteBool(w, bool(%s))", expr)
+	case reflect.String:
+		ctx.add("jsonWriteString(w, string(%s))", expr)
+	case reflect.Slice:
+		ctx.add("if %s == nil {", expr)
+		ctx.add("w.WriteString(\"null\")")
+		ctx.add("} else {")
+		ctx.encodeList(expr, t, depth)
+		ctx.add("}")
+	case reflect.Array:
+		ctx.encodeList(expr, t, depth)
+	case reflect.Ptr:
+		ctx.add("if %s == nil {", expr)
+		ctx.add("w.WriteString(\"null\")")
+		ctx.add("} else {")
+		ctx.encodeValue("(*"+expr+")", t.Elem(), depth+1, false)
+		ctx.add("}")
+	case reflect.Struct:
+		ctx.encodeStruct(expr, t, depth)
+	default:
+		panic(fmt.Sprintf("can not encode %s to JSON", t))
+	}
+}
+
+func (ctx *jsonCtx) encodeList(expr string, t reflect.Type, depth int) {
+	// encoding/json writes the byte arrays as arrays of numbers
+	if t.Elem().Kind() == reflect.Uint8 && !(ctx.goJSON && t.Kind() == reflect.Array) {
+		if t.Kind() == reflect.Array {
+			expr += "[:]"
+		}
+		ctx.add("jsonWriteBytes(w, %s)", expr)
+		return
+	}
+	ctx.add("w.WriteByte('[')")
+	ctx.add("for i%d := range %s {", depth, expr)
+	ctx.add("if i%d > 0 {w.WriteByte(',')}", depth)
+	ctx.encodeValue(fmt.Sprintf("%s[i%d]", expr, depth), t.Elem(), depth+1, false)
+	ctx.add("}")
+	ctx.add("w.WriteByte(']')")
+}
+
+func (ctx *jsonCtx) encodeStruct(expr string, t reflect.Type, depth int) {
+	fields := jsonFields(t)
+	if ctx.goJSON {
+		fields = goJSONFields(t)
+	}
+	omitEmpty := false
+	for _, f := range fields {
+		omitEmpty = omitEmpty || ctx.omitEmpty(f)
+	}
+	ctx.add("w.WriteByte('{')")
+	if omitEmpty && len(fields) > 1 {
+		// a comma is needed after any written field
+		ctx.add("comma%d := false", depth)
+	}
+	for i, f := range fields {
+		name, _ := json.Marshal(f.name)
+		fieldExpr := expr + "." + f.path
+		if !omitEmpty {
+			if i > 0 {
+				name = append([]byte{','}, name...)
+			}
+			ctx.add("w.WriteString(%q)", string(name)+":")
+			ctx.encodeValue(fieldExpr, f.Type, depth+1, false)
+			continue
+		}
+		if ctx.omitEmpty(f) {
+			ctx.add("if !(%s) {", ctx.emptyExpr(fieldExpr, f.Type))
+		}
+		if i > 0 {
+			ctx.add("if comma%d {w.WriteByte(',')}", depth)
+		}
+		ctx.add("w.WriteString(%q)", string(name)+":")
+		ctx.encodeValue(fieldExpr, f.Type, depth+1, false)
+		if i < len(fields)-1 {
+			ctx.add("comma%d = true", depth)
+		}
+		if ctx.omitEmpty(f) {
+			ctx.add("}")
+		}
+	}
+	ctx.add("w.WriteByte('}')")
+}
+
+// writeWrapped writes the call to encode, in the wrapper of amino if the
+// type is registered
+func (ctx *jsonCtx) writeWrapped(alias, expr string) {
+	name, registered := ctx.names[ctx.types[alias]]
+	registered = registered && !ctx.goJSON
+	if registered {
+		ctx.add("w.WriteString(%q)", fmt.Sprintf(`{"type":%q,"value":`, name))
+	}
+	ctx.addCall("%s(w, %s)", ctx.funcName("encode", alias), expr)
+	if registered {
+		ctx.add("w.WriteByte('}')")
+	}
+}
+
+func (ctx *jsonCtx) prepareStructFuncs(alias string) {
+	encode, marshal := ctx.funcName("encode", alias), ctx.funcName("Marshal", alias)
+	ctx.errReturn = "return err"
+	ctx.add("func %s(w *bytes.Buffer, v %s) error {", encode, alias)
+	ctx.encodeValue("v", ctx.types[alias], 0, true)
+	ctx.add("return nil")
+	ctx.add("} //End of %s", encode)
+	ctx.add("")
+	ctx.errReturn = "return nil, err"
+	ctx.add("func %s(v %s) ([]byte, error) {", marshal, alias)
+	ctx.add("w := &bytes.Buffer{}")
+	ctx.writeWrapped(alias, "v")
+	ctx.add("return w.Bytes(), nil")
+	ctx.add("} //End of %s", marshal)
+	ctx.add("")
+}
+
+func (ctx *jsonCtx) prepareIfcFuncs(alias string) {
+	encode, marshal := ctx.funcName("encode", alias), ctx.funcName("Marshal", alias)
+	ctx.errReturn = "return err"
+	ctx.add("func %s(w *bytes.Buffer, x %s) error {", encode, alias)
+	ctx.add("switch v := x.(type) {")
+	ctx.add("case nil:")
+	ctx.add("w.WriteString(\"null\")")
+	for _, impl := range ctx.implementations(ctx.types[alias]) {
+		ctx.add("case %s:", impl.typeName())
+		if _, ok := ctx.names[ctx.types[impl.alias]]; !ok && !ctx.goJSON {
+			ctx.add("return errJSONUnregistered(v)")
+			continue
+		}
+		expr := "v"
+		if impl.ptr {
+			if ctx.goJSON {
+				ctx.add("if v == nil {")
+				ctx.add("w.WriteString(\"null\")")
+				ctx.add("return nil")
+				ctx.add("}")
+			} else {
+				ctx.add("if v == nil {return errJSONNilPointer(v)}")
+			}
+			expr = "*v"
+		}
+		ctx.writeWrapped(impl.alias, expr)
+	}
+	ctx.add("default:")
+	if ctx.goJSON {
+		ctx.add("return jsonWriteGoJSON(w, v)")
+	} else {
+		ctx.add("return errJSONUnregistered(v)")
+	}
+	ctx.add("} // end of switch")
+	ctx.add("return nil")
+	ctx.add("} // end of %s", encode)
+	ctx.add("")
+	ctx.add("func %s(x %s) ([]byte, error) {", marshal, alias)
+	ctx.add("w := &bytes.Buffer{}")
+	ctx.add("if err := %s(w, x); err != nil {return nil, err}", encode)
+	ctx.add("return w.Bytes(), nil")
+	ctx.add("} // end of %s", marshal)
+	ctx.add("")
+}
+
+// prepareAnyFunc generates MarshalJSONAny, which writes the same bytes as the
+// MarshalJSON of amino
+func (ctx *jsonCtx) prepareAnyFunc() {
+	ctx.errReturn = "return nil, err"
+	ctx.add("func MarshalJSONAny(x interface{}) ([]byte, error) {")
+	ctx.add("w := &bytes.Buffer{}")
+	ctx.add("switch v := x.(type) {")
+	ctx.add("case nil:")
+	ctx.add("w.WriteString(\"null\")")
+	for _, impl := range ctx.implementations(nil) {
+		ctx.add("case %s:", impl.typeName())
+		if !impl.ptr {
+			ctx.writeWrapped(impl.alias, "v")
+			continue
+		}
+		// amino wraps a nil pointer too
+		name, registered := ctx.names[ctx.types[impl.alias]]
+		if registered {
+			ctx.add("w.WriteString(%q)", fmt.Sprintf(`{"type":%q,"value":`, name))
+		}
+		ctx.add("if v == nil {")
+		ctx.add("w.WriteString(\"null\")")
+		ctx.add("} else {")
+		ctx.addCall("encodeJSON%s(w, *v)", impl.alias)
+		ctx.add("}")
+		if registered {
+			ctx.add("w.WriteByte('}')")
+		}
+	}
+	ctx.add("default:")
+	ctx.add("return nil, errJSONUnregistered(v)")
+	ctx.add("} // end of switch")
+	ctx.add("return w.Bytes(), nil")
+	ctx.add("} // end of MarshalJSONAny")
+	ctx.add("")
+}
+
+// generateJSONFuncs writes MarshalJSONX for every type and interface in list,
+// and MarshalJSONAny for all of them. names are the names registered to amino.
+func generateJSONFuncs(w io.Writer, list []codon.AliasAndValue, leafTypes map[string]string,
+	names map[reflect.Type]string) {
+
+	ctx := &jsonCtx{typeTable: newTypeTable(list, leafTypes), names: names}
+	for _, alias := range ctx.structs {
+		ctx.prepareStructFuncs(alias)
+	}
+	for _, alias := range ctx.ifcs {
+		ctx.prepareIfcFuncs(alias)
+	}
+	ctx.prepareAnyFunc()
+	writeGeneratedLines(w, ctx.lines)
+}
+
+// reachable returns the aliases of the types which the encoders of alias
+// may encode, without the fields of the marshalers
+func (ctx *jsonCtx) reachable(alias string) map[string]bool {
+	aliases := make(map[string]bool)
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		if alias, ok := ctx.aliases[t]; ok {
+			if aliases[alias] {
+				return
+			}
+			aliases[alias] = true
+		}
+		if _, ok := jsonWriters[t]; ok || t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+			return
+		}
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Ptr:
+			visit(t.Elem())
+		case reflect.Struct:
+			for _, f := range jsonFields(t) {
+				visit(f.Type)
+			}
+		case reflect.Interface:
+			for _, impl := range ctx.implementations(t) {
+				visit(ctx.types[impl.alias])
+			}
+		}
+	}
+	visit(ctx.types[alias])
+	return aliases
+}
+
+// generateGoJSONFuncs writes MarshalGoJSONX for root and the types it may
+// encode, which writes the same bytes as json.Marshal of encoding/json
+func generateGoJSONFuncs(w io.Writer, list []codon.AliasAndValue, leafTypes map[string]string, root string) {
+	ctx := &jsonCtx{typeTable: newTypeTable(list, leafTypes), goJSON: true}
+	aliases := ctx.reachable(root)
+	for _, alias := range ctx.structs {
+		if aliases[alias] {
+			ctx.prepareStructFuncs(alias)
+		}
+	}
+	for _, alias := range ctx.ifcs {
+		if aliases[alias] {
+			ctx.prepareIfcFuncs(alias)
+		}
+	}
+	writeGeneratedLines(w, ctx.lines)
+}
@@ -0,0 +1,27 @@
+package codec
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// The previous layouts of the types, which DecodeVersionedAny can still read.
+// When the fields of a type change, its old layout is kept here as a struct
+// with the same fields as before, together with an UpgradeX function, and it
+// is added to legacyTypes in prepare.go before codec.go is regenerated.
+
+// AccountXV1 is AccountX before the referee of DEX2
+type AccountXV1 struct {
+	Address      sdk.AccAddress
+	MemoRequired bool
+	LockedCoins  []LockedCoin
+	FrozenCoins  []Coin
+}
+
+func UpgradeAccountXV1(v AccountXV1) AccountX {
+	return AccountX{
+		Address:      v.Address,
+		MemoRequired: v.MemoRequired,
+		LockedCoins:  v.LockedCoins,
+		FrozenCoins:  v.FrozenCoins,
+	}
+}
@@ -0,0 +1,268 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DecodeLimits bounds the resources the decoders spend on an input. Besides
+// them, a decoder never allocates a slice longer than its remaining input.
+type DecodeLimits struct {
+	MaxSliceLength  int
+	MaxBytesLength  int
+	MaxStringLength int
+	// the nesting depth of the Decode functions, it bounds the recursion
+	// through the interfaces, e.g. multisig pubkeys in multisig pubkeys
+	MaxDepth int
+}
+
+func DefaultDecodeLimits() DecodeLimits {
+	return DecodeLimits{
+		MaxSliceLength:  10000,
+		MaxBytesLength:  1 << 20,
+		MaxStringLength: 1 << 20,
+		MaxDepth:        32,
+	}
+}
+
+var decodeLimits = DefaultDecodeLimits()
+
+// SetDecodeLimits changes the limits of all the decoders, it should be called
+// before decoding, e.g. when the app starts
+func SetDecodeLimits(limits DecodeLimits) {
+	decodeLimits = limits
+}
+
+func GetDecodeLimits() DecodeLimits {
+	return decodeLimits
+}
+
+var (
+	ErrNotEnoughBytes    = errors.New("codon: not enough bytes to read")
+	ErrVarintOverflow    = errors.New("codon: varint overflows 64 bits")
+	ErrNonCanonical      = errors.New("codon: non-canonical encoding")
+	ErrNegativeLength    = errors.New("codon: negative length")
+	ErrArrayLength       = errors.New("codon: wrong array length")
+	ErrUnknownMagicBytes = errors.New("codon: unknown magic bytes")
+)
+
+const (
+	LimitSliceLength  = "slice length"
+	LimitBytesLength  = "bytes length"
+	LimitStringLength = "string length"
+	LimitDepth        = "nesting depth"
+)
+
+// LimitError is returned when an input exceeds one of the DecodeLimits
+type LimitError struct {
+	Limit  string
+	Max    int
+	Actual int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("codon: %s %d exceeds the limit %d", e.Limit, e.Actual, e.Max)
+}
+
+func checkLimit(limit string, max, actual int) error {
+	if actual > max {
+		return &LimitError{Limit: limit, Max: max, Actual: actual}
+	}
+	return nil
+}
+
+func checkSliceLength(length int) error {
+	return checkLimit(LimitSliceLength, decodeLimits.MaxSliceLength, length)
+}
+
+func checkDepth(depth int) error {
+	return checkLimit(LimitDepth, decodeLimits.MaxDepth, depth)
+}
+
+// The helpers below replace the ones generated by codon, which accept
+// non-canonical varints and may return a nil error after a failure.
+
+func varintError(bz []byte, n int) error {
+	if n == 0 {
+		return ErrNotEnoughBytes
+	} else if n < 0 {
+		return ErrVarintOverflow
+	} else if n > 1 && bz[n-1] == 0 {
+		// a trailing zero byte could be dropped
+		return ErrNonCanonical
+	}
+	return nil
+}
+
+func codonDecodeBool(bz []byte, n *int, err *error) bool {
+	if len(bz) < 1 {
+		*err = ErrNotEnoughBytes
+		return false
+	}
+	if bz[0] > 1 {
+		*err = ErrNonCanonical
+		return false
+	}
+	*n = 1
+	*err = nil
+	return bz[0] != 0
+}
+
+func codonDecodeInt(bz []byte, n *int, err *error) int {
+	i := codonDecodeInt64(bz, n, err)
+	if *err == nil && int64(int(i)) != i {
+		*err = ErrVarintOverflow
+	}
+	return int(i)
+}
+
+// codonDecodeLength decodes the length of a slice, which can not be longer than
+// the remaining bytes since every element takes at least one byte
+func codonDecodeLength(bz []byte, n *int, err *error) int {
+	length := codonDecodeInt64(bz, n, err)
+	if *err != nil {
+		return 0
+	}
+	if length < 0 {
+		*err = ErrNegativeLength
+		return 0
+	}
+	if length > int64(len(bz)-*n) {
+		*err = ErrNotEnoughBytes
+		return 0
+	}
+	return int(length)
+}
+
+func codonDecodeInt8(bz []byte, n *int, err *error) int8 {
+	if len(bz) < 1 {
+		*err = ErrNotEnoughBytes
+		return 0
+	}
+	*n = 1
+	*err = nil
+	return int8(bz[0])
+}
+
+func codonDecodeInt16(bz []byte, n *int, err *error) int16 {
+	if len(bz) < 2 {
+		*err = ErrNotEnoughBytes
+		return 0
+	}
+	*n = 2
+	*err = nil
+	return int16(binary.LittleEndian.Uint16(bz[:2]))
+}
+
+func codonDecodeInt32(bz []byte, n *int, err *error) int32 {
+	i := codonDecodeInt64(bz, n, err)
+	if *err == nil && int64(int32(i)) != i {
+		*err = ErrVarintOverflow
+	}
+	return int32(i)
+}
+
+func codonDecodeInt64(bz []byte, n *int, err *error) int64 {
+	i, m := binary.Varint(bz)
+	*err = varintError(bz, m)
+	if *err != nil {
+		return 0
+	}
+	*n = m
+	return i
+}
+
+func codonDecodeUint(bz []byte, n *int, err *error) uint {
+	i := codonDecodeUint64(bz, n, err)
+	if *err == nil && uint64(uint(i)) != i {
+		*err = ErrVarintOverflow
+	}
+	return uint(i)
+}
+
+func codonDecodeUint8(bz []byte, n *int, err *error) uint8 {
+	if len(bz) < 1 {
+		*err = ErrNotEnoughBytes
+		return 0
+	}
+	*n = 1
+	*err = nil
+	return bz[0]
+}
+
+func codonDecodeUint16(bz []byte, n *int, err *error) uint16 {
+	if len(bz) < 2 {
+		*err = ErrNotEnoughBytes
+		return 0
+	}
+	*n = 2
+	*err = nil
+	return binary.LittleEndian.Uint16(bz[:2])
+}
+
+func codonDecodeUint32(bz []byte, n *int, err *error) uint32 {
+	i := codonDecodeUint64(bz, n, err)
+	if *err == nil && uint64(uint32(i)) != i {
+		*err = ErrVarintOverflow
+	}
+	return uint32(i)
+}
+
+func codonDecodeUint64(bz []byte, n *int, err *error) uint64 {
+	i, m := binary.Uvarint(bz)
+	*err = varintError(bz, m)
+	if *err != nil {
+		return 0
+	}
+	*n = m
+	return i
+}
+
+func codonDecodeFloat64(bz []byte, n *int, err *error) float64 {
+	if len(bz) < 8 {
+		*err = ErrNotEnoughBytes
+		return 0
+	}
+	*n = 8
+	*err = nil
+	return math.Float64frombits(binary.LittleEndian.Uint64(bz[:8]))
+}
+
+func codonDecodeFloat32(bz []byte, n *int, err *error) float32 {
+	if len(bz) < 4 {
+		*err = ErrNotEnoughBytes
+		return 0
+	}
+	*n = 4
+	*err = nil
+	return math.Float32frombits(binary.LittleEndian.Uint32(bz[:4]))
+}
+
+func codonGetByteSlice(bz []byte, length int) ([]byte, int, error) {
+	if err := checkLimit(LimitBytesLength, decodeLimits.MaxBytesLength, length); err != nil {
+		return nil, 0, err
+	}
+	if length < 0 {
+		return nil, 0, ErrNegativeLength
+	}
+	if len(bz) < length {
+		return nil, 0, ErrNotEnoughBytes
+	}
+	return bz[:length], length, nil
+}
+
+func codonDecodeString(bz []byte, n *int, err *error) string {
+	var m int
+	length := codonDecodeLength(bz, &m, err)
+	if *err != nil {
+		return ""
+	}
+	*err = checkLimit(LimitStringLength, decodeLimits.MaxStringLength, length)
+	if *err != nil {
+		return ""
+	}
+	*n = m + length
+	return string(bz[m : m+length])
+}
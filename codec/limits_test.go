@@ -0,0 +1,176 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinexchain/dex/codec"
+)
+
+const adversarialSize = 1024
+
+// a decoder must not allocate much more than this on a 1 KB input
+const maxAdversarialAlloc = 64 * 1024
+
+type decodeFunc func(bz []byte) (interface{}, int, error)
+
+// decodeSafely returns the decoded value and the bytes allocated by decode,
+// a panic fails the test
+func decodeSafely(t *testing.T, decode decodeFunc, bz []byte) (v interface{}, n int, err error, allocated uint64) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("decoder panics on %x: %v", bz, r)
+		}
+	}()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	v, n, err = decode(bz)
+	runtime.ReadMemStats(&after)
+	return v, n, err, after.TotalAlloc - before.TotalAlloc
+}
+
+func varint(i int64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutVarint(buf, i)]
+}
+
+// pad fills bz up to 1 KB
+func pad(bz []byte, b byte) []byte {
+	for len(bz) < adversarialSize {
+		bz = append(bz, b)
+	}
+	return bz
+}
+
+func magicBytesOf(t *testing.T, v interface{}) []byte {
+	var buf bytes.Buffer
+	require.Nil(t, codec.EncodeAny(&buf, v))
+	return buf.Bytes()[:4]
+}
+
+func decodeAny(bz []byte) (interface{}, int, error) {
+	return codec.DecodeAny(bz)
+}
+
+func requireLimitError(t *testing.T, err error, limit string) {
+	var limitErr *codec.LimitError
+	require.True(t, errors.As(err, &limitErr), "%v", err)
+	require.Equal(t, limit, limitErr.Limit)
+}
+
+func withLimits(limits codec.DecodeLimits, f func()) {
+	defer codec.SetDecodeLimits(codec.GetDecodeLimits())
+	codec.SetDecodeLimits(limits)
+	f()
+}
+
+func TestDecodeLimits(t *testing.T) {
+	stdTx := magicBytesOf(t, codec.StdTx{})
+	accAddress := magicBytesOf(t, codec.AccAddress{})
+	coin := magicBytesOf(t, codec.Coin{})
+	multisig := magicBytesOf(t, codec.PubKeyMultisigThreshold{})
+	ed25519 := magicBytesOf(t, codec.PubKeyEd25519{})
+
+	// a multisig pubkey of a multisig pubkey of ...
+	var nested []byte
+	for len(nested) < adversarialSize {
+		nested = append(nested, multisig...)
+		nested = append(nested, 1) // K
+		nested = append(nested, varint(1)...)
+	}
+
+	limits := codec.DefaultDecodeLimits()
+	limits.MaxSliceLength = 100
+	limits.MaxBytesLength = 100
+	limits.MaxStringLength = 100
+
+	testCases := []struct {
+		name  string
+		input []byte
+		err   error
+		limit string
+	}{
+		{"huge slice", pad(append(stdTx, varint(1<<40)...), 0), codec.ErrNotEnoughBytes, ""},
+		{"negative slice", pad(append(stdTx, varint(-1)...), 0), codec.ErrNegativeLength, ""},
+		{"long slice", pad(append(stdTx, varint(900)...), 0), nil, codec.LimitSliceLength},
+		{"huge bytes", pad(append(accAddress, varint(1<<40)...), 0), codec.ErrNotEnoughBytes, ""},
+		{"long bytes", pad(append(accAddress, varint(900)...), 0), nil, codec.LimitBytesLength},
+		{"long string", pad(append(coin, varint(900)...), 'a'), nil, codec.LimitStringLength},
+		{"deep nesting", nested, nil, codec.LimitDepth},
+		{"wrong array length", pad(append(ed25519, varint(33)...), 0), codec.ErrArrayLength, ""},
+		{"overflowed varint", pad(accAddress, 0xff), codec.ErrVarintOverflow, ""},
+		{"non-canonical varint", pad(append(accAddress, 0x80, 0), 0), codec.ErrNonCanonical, ""},
+		{"unknown magic bytes", pad(nil, 0), codec.ErrUnknownMagicBytes, ""},
+		{"short magic bytes", []byte{1}, codec.ErrNotEnoughBytes, ""},
+	}
+	withLimits(limits, func() {
+		for _, tc := range testCases {
+			_, _, err, allocated := decodeSafely(t, decodeAny, tc.input)
+			if tc.limit != "" {
+				requireLimitError(t, err, tc.limit)
+			} else {
+				require.Equal(t, tc.err, err, tc.name)
+			}
+			require.True(t, allocated <= maxAdversarialAlloc, "%s: %d bytes allocated", tc.name, allocated)
+		}
+	})
+}
+
+func TestDecodeDefaultLimits(t *testing.T) {
+	limits := codec.DefaultDecodeLimits()
+	require.Equal(t, limits, codec.GetDecodeLimits())
+
+	// the slice and byte lengths are bounded by the input, not by the limits
+	stdTx := magicBytesOf(t, codec.StdTx{})
+	_, _, err, allocated := decodeSafely(t, decodeAny, pad(append(stdTx, varint(1000)...), 0))
+	require.NotNil(t, err)
+	require.True(t, allocated <= maxAdversarialAlloc, "%d bytes allocated", allocated)
+
+	// the depth limit is reached before the end of a 1 KB input
+	multisig := magicBytesOf(t, codec.PubKeyMultisigThreshold{})
+	var nested []byte
+	for i := 0; i <= limits.MaxDepth; i++ {
+		nested = append(nested, multisig...)
+		nested = append(nested, 1)
+		nested = append(nested, varint(1)...)
+	}
+	require.True(t, len(nested) < adversarialSize)
+	_, _, err = codec.DecodePubKey(nested)
+	requireLimitError(t, err, codec.LimitDepth)
+}
+
+// TestAdversarialInputs corrupts the encodings of random values into 1 KB
+// inputs, none of them may panic or allocate much
+func TestAdversarialInputs(t *testing.T) {
+	r := codec.NewRandSrc(1)
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		var buf bytes.Buffer
+		require.Nil(t, codec.EncodeAny(&buf, codec.RandAny(r)))
+		bz := buf.Bytes()
+		if len(bz) > adversarialSize {
+			bz = bz[:adversarialSize]
+		}
+		// overwrite a random range after the magic bytes with a byte which
+		// makes large varints or with random bytes
+		input := pad(append([]byte(nil), bz...), byte(rnd.Intn(256)))
+		start := 4 + rnd.Intn(adversarialSize-4)
+		end := start + rnd.Intn(adversarialSize-start)
+		fill := []byte{0xff, 0x7f, 0x80, byte(rnd.Intn(256))}[rnd.Intn(4)]
+		for j := start; j <= end && j < adversarialSize; j++ {
+			if fill == 0x80 {
+				input[j] = byte(rnd.Intn(256))
+			} else {
+				input[j] = fill
+			}
+		}
+		_, _, _, allocated := decodeSafely(t, decodeAny, input)
+		require.True(t, allocated <= maxAdversarialAlloc, "%d bytes allocated to decode %x", allocated, input)
+	}
+}
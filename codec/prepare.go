@@ -1,171 +1,192 @@
 package codec
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	amino "github.com/tendermint/go-amino"
 
 	"github.com/coinexchain/codon"
 )
 
-func ShowInfo() {
-	leafTypes := GetLeafTypes()
+// the interfaces, their implementations are found among the other types
+var interfaceTypes = []codon.AliasAndValue{
+	{Alias: "PubKey", Value: (*PubKey)(nil)},
+	{Alias: "Msg", Value: (*Msg)(nil)},
+	{Alias: "Account", Value: (*Account)(nil)},
+	{Alias: "Content", Value: (*Content)(nil)},
+}
+
+// the types which are not registered to amino but are used by the registered ones
+var unregisteredTypes = []codon.AliasAndValue{
+	{Alias: "SignedMsgType", Value: SignedMsgType(0)},
+	{Alias: "VoteOption", Value: VoteOption(0)},
+	{Alias: "Vote", Value: Vote{}},
+
+	{Alias: "Coin", Value: Coin{}},
+	{Alias: "LockedCoin", Value: LockedCoin{}},
+	{Alias: "StdSignature", Value: StdSignature{}},
+	{Alias: "ParamChange", Value: ParamChange{}},
+	{Alias: "Input", Value: Input{}},
+	{Alias: "Output", Value: Output{}},
+	{Alias: "AccAddress", Value: AccAddress{}},
+	{Alias: "CommentRef", Value: CommentRef{}},
+}
+
+// legacyType is a previous layout of the type aliased as Current, see legacy.go
+type legacyType struct {
+	Alias   string
+	Value   interface{}
+	Current string
+}
 
-	//ShowInfo("",Account{})
-
-	codon.ShowInfoForVar(leafTypes, DuplicateVoteEvidence{})
-	codon.ShowInfoForVar(leafTypes, PrivKeyEd25519{})
-	codon.ShowInfoForVar(leafTypes, PrivKeySecp256k1{})
-	codon.ShowInfoForVar(leafTypes, PubKeyEd25519{})
-	codon.ShowInfoForVar(leafTypes, PubKeySecp256k1{})
-	codon.ShowInfoForVar(leafTypes, PubKeyMultisigThreshold{})
-
-	codon.ShowInfoForVar(leafTypes, BaseVestingAccount{})
-	codon.ShowInfoForVar(leafTypes, ContinuousVestingAccount{})
-	codon.ShowInfoForVar(leafTypes, DelayedVestingAccount{})
-	codon.ShowInfoForVar(leafTypes, ModuleAccount{})
-	codon.ShowInfoForVar(leafTypes, StdTx{})
-	codon.ShowInfoForVar(leafTypes, MsgBeginRedelegate{})
-	codon.ShowInfoForVar(leafTypes, MsgCreateValidator{})
-	codon.ShowInfoForVar(leafTypes, MsgDelegate{})
-	codon.ShowInfoForVar(leafTypes, MsgEditValidator{})
-	codon.ShowInfoForVar(leafTypes, MsgSetWithdrawAddress{})
-	codon.ShowInfoForVar(leafTypes, MsgUndelegate{})
-	codon.ShowInfoForVar(leafTypes, MsgUnjail{})
-	codon.ShowInfoForVar(leafTypes, MsgWithdrawDelegatorReward{})
-	codon.ShowInfoForVar(leafTypes, MsgWithdrawValidatorCommission{})
-	codon.ShowInfoForVar(leafTypes, MsgDeposit{})
-	codon.ShowInfoForVar(leafTypes, MsgSubmitProposal{})
-	codon.ShowInfoForVar(leafTypes, MsgVote{})
-	codon.ShowInfoForVar(leafTypes, ParameterChangeProposal{})
-	codon.ShowInfoForVar(leafTypes, SoftwareUpgradeProposal{})
-	codon.ShowInfoForVar(leafTypes, TextProposal{})
-	codon.ShowInfoForVar(leafTypes, CommunityPoolSpendProposal{})
-	codon.ShowInfoForVar(leafTypes, MsgMultiSend{})
-	codon.ShowInfoForVar(leafTypes, MsgSend{})
-	codon.ShowInfoForVar(leafTypes, MsgVerifyInvariant{})
-	codon.ShowInfoForVar(leafTypes, Supply{})
-
-	codon.ShowInfoForVar(leafTypes, AccountX{})
-	codon.ShowInfoForVar(leafTypes, MsgMultiSendX{})
-	codon.ShowInfoForVar(leafTypes, MsgSendX{})
-	codon.ShowInfoForVar(leafTypes, MsgSetMemoRequired{})
-	codon.ShowInfoForVar(leafTypes, BaseToken{})
-	codon.ShowInfoForVar(leafTypes, MsgAddTokenWhitelist{})
-	codon.ShowInfoForVar(leafTypes, MsgBurnToken{})
-	codon.ShowInfoForVar(leafTypes, MsgForbidAddr{})
-	codon.ShowInfoForVar(leafTypes, MsgForbidToken{})
-	codon.ShowInfoForVar(leafTypes, MsgIssueToken{})
-	codon.ShowInfoForVar(leafTypes, MsgMintToken{})
-	codon.ShowInfoForVar(leafTypes, MsgModifyTokenInfo{})
-	codon.ShowInfoForVar(leafTypes, MsgRemoveTokenWhitelist{})
-	codon.ShowInfoForVar(leafTypes, MsgTransferOwnership{})
-	codon.ShowInfoForVar(leafTypes, MsgUnForbidAddr{})
-	codon.ShowInfoForVar(leafTypes, MsgUnForbidToken{})
-	codon.ShowInfoForVar(leafTypes, MsgBancorCancel{})
-	codon.ShowInfoForVar(leafTypes, MsgBancorInit{})
-	codon.ShowInfoForVar(leafTypes, MsgBancorTrade{})
-	codon.ShowInfoForVar(leafTypes, MsgCancelOrder{})
-	codon.ShowInfoForVar(leafTypes, MsgCancelTradingPair{})
-	codon.ShowInfoForVar(leafTypes, MsgCreateOrder{})
-	codon.ShowInfoForVar(leafTypes, MsgCreateTradingPair{})
-	codon.ShowInfoForVar(leafTypes, MsgModifyPricePrecision{})
-	codon.ShowInfoForVar(leafTypes, Order{})
-	codon.ShowInfoForVar(leafTypes, MarketInfo{})
-	codon.ShowInfoForVar(leafTypes, &MsgDonateToCommunityPool{})
-	codon.ShowInfoForVar(leafTypes, &MsgCommentToken{})
-	codon.ShowInfoForVar(leafTypes, &State{})
-	codon.ShowInfoForVar(leafTypes, &MsgAliasUpdate{})
+var legacyTypes = []legacyType{
+	{Alias: "AccountXV1", Value: AccountXV1{}, Current: "AccountX"},
 }
 
-func GenerateCodecFile(w io.Writer) {
-	list := []codon.AliasAndValue{
-		{Alias: "PubKey", Value: (*PubKey)(nil)},
-		{Alias: "Msg", Value: (*Msg)(nil)},
-		{Alias: "Account", Value: (*Account)(nil)},
-		{Alias: "Content", Value: (*Content)(nil)},
-
-		{Alias: "DuplicateVoteEvidence", Value: DuplicateVoteEvidence{}},
-		{Alias: "PrivKeyEd25519", Value: PrivKeyEd25519{}},
-		{Alias: "PrivKeySecp256k1", Value: PrivKeySecp256k1{}},
-		{Alias: "PubKeyEd25519", Value: PubKeyEd25519{}},
-		{Alias: "PubKeySecp256k1", Value: PubKeySecp256k1{}},
-		{Alias: "PubKeyMultisigThreshold", Value: PubKeyMultisigThreshold{}},
-		{Alias: "SignedMsgType", Value: SignedMsgType(0)},
-		{Alias: "VoteOption", Value: VoteOption(0)},
-		{Alias: "Vote", Value: Vote{}},
-
-		{Alias: "Coin", Value: Coin{}},
-		{Alias: "LockedCoin", Value: LockedCoin{}},
-		{Alias: "StdSignature", Value: StdSignature{}},
-		{Alias: "ParamChange", Value: ParamChange{}},
-		{Alias: "Input", Value: Input{}},
-		{Alias: "Output", Value: Output{}},
-		{Alias: "AccAddress", Value: AccAddress{}},
-		{Alias: "CommentRef", Value: CommentRef{}},
-
-		{Alias: "BaseAccount", Value: BaseAccount{}},
-		{Alias: "BaseVestingAccount", Value: BaseVestingAccount{}},
-		{Alias: "ContinuousVestingAccount", Value: ContinuousVestingAccount{}},
-		{Alias: "DelayedVestingAccount", Value: DelayedVestingAccount{}},
-		{Alias: "ModuleAccount", Value: ModuleAccount{}},
-		{Alias: "StdTx", Value: StdTx{}},
-		{Alias: "MsgBeginRedelegate", Value: MsgBeginRedelegate{}},
-		{Alias: "MsgCreateValidator", Value: MsgCreateValidator{}},
-		{Alias: "MsgDelegate", Value: MsgDelegate{}},
-		{Alias: "MsgEditValidator", Value: MsgEditValidator{}},
-		{Alias: "MsgSetWithdrawAddress", Value: MsgSetWithdrawAddress{}},
-		{Alias: "MsgUndelegate", Value: MsgUndelegate{}},
-		{Alias: "MsgUnjail", Value: MsgUnjail{}},
-		{Alias: "MsgWithdrawDelegatorReward", Value: MsgWithdrawDelegatorReward{}},
-		{Alias: "MsgWithdrawValidatorCommission", Value: MsgWithdrawValidatorCommission{}},
-		{Alias: "MsgDeposit", Value: MsgDeposit{}},
-		{Alias: "MsgSubmitProposal", Value: MsgSubmitProposal{}},
-		{Alias: "MsgVote", Value: MsgVote{}},
-		{Alias: "ParameterChangeProposal", Value: ParameterChangeProposal{}},
-		{Alias: "SoftwareUpgradeProposal", Value: SoftwareUpgradeProposal{}},
-		{Alias: "TextProposal", Value: TextProposal{}},
-		{Alias: "CommunityPoolSpendProposal", Value: CommunityPoolSpendProposal{}},
-		{Alias: "MsgMultiSend", Value: MsgMultiSend{}},
-		{Alias: "MsgSend", Value: MsgSend{}},
-		{Alias: "MsgVerifyInvariant", Value: MsgVerifyInvariant{}},
-		{Alias: "Supply", Value: Supply{}},
-
-		{Alias: "AccountX", Value: AccountX{}},
-		{Alias: "MsgMultiSendX", Value: MsgMultiSendX{}},
-		{Alias: "MsgSendX", Value: MsgSendX{}},
-		{Alias: "MsgSetMemoRequired", Value: MsgSetMemoRequired{}},
-		{Alias: "BaseToken", Value: BaseToken{}},
-		{Alias: "MsgAddTokenWhitelist", Value: MsgAddTokenWhitelist{}},
-		{Alias: "MsgBurnToken", Value: MsgBurnToken{}},
-		{Alias: "MsgForbidAddr", Value: MsgForbidAddr{}},
-		{Alias: "MsgForbidToken", Value: MsgForbidToken{}},
-		{Alias: "MsgIssueToken", Value: MsgIssueToken{}},
-		{Alias: "MsgMintToken", Value: MsgMintToken{}},
-		{Alias: "MsgModifyTokenInfo", Value: MsgModifyTokenInfo{}},
-		{Alias: "MsgRemoveTokenWhitelist", Value: MsgRemoveTokenWhitelist{}},
-		{Alias: "MsgTransferOwnership", Value: MsgTransferOwnership{}},
-		{Alias: "MsgUnForbidAddr", Value: MsgUnForbidAddr{}},
-		{Alias: "MsgUnForbidToken", Value: MsgUnForbidToken{}},
-		{Alias: "MsgBancorCancel", Value: MsgBancorCancel{}},
-		{Alias: "MsgBancorInit", Value: MsgBancorInit{}},
-		{Alias: "MsgBancorTrade", Value: MsgBancorTrade{}},
-		{Alias: "MsgCancelOrder", Value: MsgCancelOrder{}},
-		{Alias: "MsgCancelTradingPair", Value: MsgCancelTradingPair{}},
-		{Alias: "MsgCreateOrder", Value: MsgCreateOrder{}},
-		{Alias: "MsgCreateTradingPair", Value: MsgCreateTradingPair{}},
-		{Alias: "MsgModifyPricePrecision", Value: MsgModifyPricePrecision{}},
-		{Alias: "Order", Value: Order{}},
-		{Alias: "MarketInfo", Value: MarketInfo{}},
-		{Alias: "MsgDonateToCommunityPool", Value: MsgDonateToCommunityPool{}},
-		{Alias: "MsgCommentToken", Value: MsgCommentToken{}},
-		{Alias: "State", Value: State{}},
-		{Alias: "MsgAliasUpdate", Value: MsgAliasUpdate{}},
+// the aliases in types.go of the registered types whose names are used by
+// other types, the other registered types are aliased by their names
+var conflictedAliases = map[string]string{
+	"github.com/coinexchain/cet-sdk/modules/bankx/internal/types.MsgMultiSend": "MsgMultiSendX",
+	"github.com/coinexchain/cet-sdk/modules/bankx/internal/types.MsgSend":      "MsgSendX",
+}
+
+// RegisteredType is a concrete type registered to the amino codec of the app,
+// aliased by its name in types.go
+type RegisteredType struct {
+	Alias  string
+	Name   string // the amino name, which is written by amino JSON
+	Prefix []byte // the prefix bytes of amino binary
+	Value  interface{}
+	// registered as a pointer, amino decodes it to a pointer in interfaces
+	Pointer bool
+}
+
+// anyRegistered is implemented by all the concrete types registered to amino,
+// which constructs them from their names when decoding it
+type anyRegistered interface{}
+
+// GetRegisteredTypes returns the concrete types registered to cdc, sorted by
+// their aliases. The types are read from cdc.PrintTypes and constructed by
+// amino, an interface is registered to cdc for it, so cdc should not be used
+// for anything else.
+func GetRegisteredTypes(cdc *amino.Codec) ([]RegisteredType, error) {
+	var buf bytes.Buffer
+	if err := cdc.PrintTypes(&buf); err != nil {
+		return nil, err
 	}
+	cdc.RegisterInterface((*anyRegistered)(nil), nil)
+	var registered []RegisteredType
+	// the first two lines are the header of the table
+	for _, line := range strings.Split(buf.String(), "\n")[2:] {
+		columns := strings.Split(line, "|")
+		if len(columns) < 4 {
+			continue
+		}
+		name := strings.TrimSpace(columns[2])
+		prefix, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(columns[3]), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix bytes of %s: %v", name, err)
+		}
+		// a null value is decoded as the zero value of any type
+		var v anyRegistered
+		if err := cdc.UnmarshalJSON([]byte(fmt.Sprintf(`{"type":%q,"value":null}`, name)), &v); err != nil {
+			return nil, fmt.Errorf("can not construct %s: %v", name, err)
+		}
+		t := reflect.TypeOf(v)
+		pointer := t.Kind() == reflect.Ptr
+		if pointer {
+			t = t.Elem()
+		}
+		alias, ok := conflictedAliases[t.PkgPath()+"."+t.Name()]
+		if !ok {
+			alias = t.Name()
+		}
+		registered = append(registered, RegisteredType{
+			Alias:   alias,
+			Name:    name,
+			Prefix:  prefix,
+			Value:   reflect.Zero(t).Interface(),
+			Pointer: pointer,
+		})
+	}
+	sort.Slice(registered, func(i, j int) bool {
+		return registered[i].Alias < registered[j].Alias
+	})
+	for i := 1; i < len(registered); i++ {
+		if registered[i].Alias == registered[i-1].Alias {
+			return nil, fmt.Errorf("%s and %s are both aliased as %s, add one to conflictedAliases",
+				registered[i-1].Name, registered[i].Name, registered[i].Alias)
+		}
+	}
+	return registered, nil
+}
 
-	extraImports := []string{`"time"`, `sdk "github.com/cosmos/cosmos-sdk/types"`}
+// getRegisteredNames returns the amino names of the registered types
+func getRegisteredNames(registered []RegisteredType) map[reflect.Type]string {
+	names := make(map[reflect.Type]string, len(registered))
+	for _, rt := range registered {
+		names[reflect.TypeOf(rt.Value)] = rt.Name
+	}
+	return names
+}
+
+// GetCodecTypes returns the types supported by the generated codec, i.e. the
+// interfaces, the registered concrete types and the types they use
+func GetCodecTypes(registered []RegisteredType) []codon.AliasAndValue {
+	list := make([]codon.AliasAndValue, 0, len(interfaceTypes)+len(unregisteredTypes)+len(registered))
+	list = append(list, interfaceTypes...)
+	list = append(list, unregisteredTypes...)
+	for _, rt := range registered {
+		list = append(list, codon.AliasAndValue{Alias: rt.Alias, Value: rt.Value})
+	}
+	return list
+}
+
+func ShowInfo(cdc *amino.Codec) {
+	registered, err := GetRegisteredTypes(cdc)
+	if err != nil {
+		panic(err)
+	}
+	leafTypes := GetLeafTypes()
+	for _, entry := range GetCodecTypes(registered) {
+		if reflect.TypeOf(entry.Value).Kind() != reflect.Ptr {
+			codon.ShowInfoForVar(leafTypes, entry.Value)
+		}
+	}
+}
+
+// GenerateCodecFile writes codec.go for the types registered to cdc, the codec
+// of the app. The generated decoders are hardened by hardenDecoders, and the
+// encoders are copied by bufferEncoders.
+func GenerateCodecFile(w io.Writer, cdc *amino.Codec) {
+	registered, err := GetRegisteredTypes(cdc)
+	if err != nil {
+		panic(err)
+	}
+	list := GetCodecTypes(registered)
+	extraImports := []string{`"bytes"`, `"time"`, `sdk "github.com/cosmos/cosmos-sdk/types"`}
 	ignoreImpl := make(map[string]string)
 	ignoreImpl["StdSignature"] = "PubKey"
 	ignoreImpl["PubKeyMultisigThreshold"] = "PubKey"
-	codon.GenerateCodecFile(w, GetLeafTypes(), ignoreImpl, list, extraLogics, extraImports)
+	var buf bytes.Buffer
+	codon.GenerateCodecFile(&buf, GetLeafTypes(), ignoreImpl, list, extraLogics, extraImports)
+	src := resolveMagicBytes(hardenDecoders(buf.String()))
+	if _, err := io.WriteString(w, src+bufferEncoders(src)); err != nil {
+		panic(err)
+	}
+	generateCopyEqualFuncs(w, list, GetLeafTypes())
+	generateJSONFuncs(w, list, GetLeafTypes(), getRegisteredNames(registered))
+	// the notifications of the txs are encoded by encoding/json
+	generateGoJSONFuncs(w, list, GetLeafTypes(), "StdTx")
+	generateSchemaFuncs(w, list, GetLeafTypes())
+	generateAminoFuncs(w, list, GetLeafTypes(), registered)
+	generateLegacyFuncs(w, list, GetLeafTypes(), ignoreImpl, extraImports)
 }
 
 func GetLeafTypes() map[string]string {
@@ -218,42 +239,36 @@ func EncodeTime(w io.Writer, t time.Time) error {
 }
 
 func DecodeTime(bz []byte) (time.Time, int, error) {
-	sec, n := binary.Varint(bz)
+	var n, m int
 	var err error
-	if n == 0 {
-		// buf too small
-		err = errors.New("buffer too small")
-	} else if n < 0 {
-		// value larger than 64 bits (overflow)
-		// and -n is the number of bytes read
-		n = -n
-		err = errors.New("EOF decoding varint")
-	}
-	if err!=nil {
-		return time.Unix(sec,0), n, err
+	sec := codonDecodeInt64(bz, &n, &err)
+	if err != nil {
+		return time.Time{}, n, err
 	}
-
-	nanosec, m := binary.Varint(bz[n:])
-	if m == 0 {
-		// buf too small
-		err = errors.New("buffer too small")
-	} else if m < 0 {
-		// value larger than 64 bits (overflow)
-		// and -m is the number of bytes read
-		m = -m
-		err = errors.New("EOF decoding varint")
+	nanosec := codonDecodeInt64(bz[n:], &m, &err)
+	if err != nil {
+		return time.Time{}, n+m, err
 	}
-	if err!=nil {
-		return time.Unix(sec,nanosec), n+m, err
+	t := time.Unix(sec, nanosec).UTC()
+	if nanosec < 0 || nanosec >= int64(time.Second) || t.Unix() != sec {
+		// EncodeTime would not write the same bytes
+		return time.Time{}, n+m, ErrNonCanonical
 	}
-
-	return time.Unix(sec, nanosec).UTC(), n+m, nil
+	return t, n+m, nil
 }
 
 func RandTime(r RandSrc) time.Time {
 	return time.Unix(r.GetInt64(), r.GetInt64()).UTC()
 }
 
+func DeepCopyTime(t time.Time) time.Time {
+	return t
+}
+
+func EqualTime(a, b time.Time) bool {
+	return a.Equal(b)
+}
+
 func EncodeInt(w io.Writer, v sdk.Int) error {
 	s, err := v.MarshalAmino()
 	if err!=nil {
@@ -275,6 +290,9 @@ func DecodeInt(bz []byte) (sdk.Int, int, error) {
 	if err!=nil {
 		return v, n, err
 	}
+	if canonical, _ := v.MarshalAmino(); canonical != s {
+		return v, n, ErrNonCanonical
+	}
 
 	return v, n, nil
 }
@@ -288,6 +306,20 @@ func RandInt(r RandSrc) sdk.Int {
 	return res
 }
 
+func DeepCopyInt(v sdk.Int) sdk.Int {
+	if v == (sdk.Int{}) {
+		return v
+	}
+	return sdk.NewIntFromBigInt(v.BigInt())
+}
+
+func EqualInt(a, b sdk.Int) bool {
+	if a == (sdk.Int{}) || b == (sdk.Int{}) {
+		return a == b
+	}
+	return a.Equal(b)
+}
+
 func EncodeDec(w io.Writer, v sdk.Dec) error {
 	s, err := v.MarshalAmino()
 	if err!=nil {
@@ -309,6 +341,9 @@ func DecodeDec(bz []byte) (sdk.Dec, int, error) {
 	if err!=nil {
 		return v, n, err
 	}
+	if canonical, _ := v.MarshalAmino(); canonical != s {
+		return v, n, ErrNonCanonical
+	}
 
 	return v, n, nil
 }
@@ -322,6 +357,20 @@ func RandDec(r RandSrc) sdk.Dec {
 	res = res.QuoInt64(r.GetInt64()&0xFFFFFFFF)
 	return res
 }
+
+func DeepCopyDec(v sdk.Dec) sdk.Dec {
+	if v.IsNil() {
+		return v
+	}
+	return sdk.NewDecFromBigIntWithPrec(v.Int, sdk.Precision)
+}
+
+func EqualDec(a, b sdk.Dec) bool {
+	if a.IsNil() || b.IsNil() {
+		return a.IsNil() == b.IsNil()
+	}
+	return a.Equal(b)
+}
 `
 
 /*
@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"math/rand"
+)
+
+var _ RandSrc = mathRandSrc{}
+
+// mathRandSrc is a deterministic RandSrc, unlike randsrc it needs no file
+type mathRandSrc struct {
+	*rand.Rand
+}
+
+// NewRandSrc returns a RandSrc seeded by seed, for the tests and the seed
+// corpora of the fuzz tests
+func NewRandSrc(seed int64) RandSrc {
+	return mathRandSrc{Rand: rand.New(rand.NewSource(seed))}
+}
+
+func (r mathRandSrc) GetBool() bool       { return r.Intn(2) == 1 }
+func (r mathRandSrc) GetInt() int         { return int(r.Uint64()) }
+func (r mathRandSrc) GetInt8() int8       { return int8(r.Uint64()) }
+func (r mathRandSrc) GetInt16() int16     { return int16(r.Uint64()) }
+func (r mathRandSrc) GetInt32() int32     { return int32(r.Uint64()) }
+func (r mathRandSrc) GetUint() uint       { return uint(r.Uint64()) }
+func (r mathRandSrc) GetUint8() uint8     { return uint8(r.Uint64()) }
+func (r mathRandSrc) GetUint16() uint16   { return uint16(r.Uint64()) }
+func (r mathRandSrc) GetUint32() uint32   { return r.Uint32() }
+func (r mathRandSrc) GetUint64() uint64   { return r.Uint64() }
+func (r mathRandSrc) GetFloat32() float32 { return r.Float32() }
+func (r mathRandSrc) GetFloat64() float64 { return r.Float64() }
+
+// half of the int64 values are small, so that the random times are often in
+// the range supported by amino
+func (r mathRandSrc) GetInt64() int64 {
+	if r.GetBool() {
+		return int64(r.Uint64())
+	}
+	return r.Int63n(1<<34) - 1<<33
+}
+
+func (r mathRandSrc) GetString(n int) string {
+	return string(r.GetBytes(n))
+}
+
+func (r mathRandSrc) GetBytes(n int) []byte {
+	bz := make([]byte, n)
+	_, _ = r.Read(bz)
+	return bz
+}
@@ -3,14 +3,15 @@ package main
 import (
 	"os"
 
+	"github.com/coinexchain/dex/app"
 	"github.com/coinexchain/dex/codec"
 )
 
 func main() {
-	//codec.ShowInfo()
+	//codec.ShowInfo(app.MakeCodec())
 	genCode()
 }
 
 func genCode() {
-	codec.GenerateCodecFile(os.Stdout)
+	codec.GenerateCodecFile(os.Stdout, app.MakeCodec())
 }
@@ -0,0 +1,139 @@
+package codec
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/coinexchain/codon"
+)
+
+// The encoding of codon has no field tags, a struct is decoded by the order
+// and the types of its fields. The schema hash of a type is taken from this
+// layout, so any change which makes the old bytes undecodable changes the
+// hash. The values in an interface carry their own magic bytes, only the
+// name of the interface is part of the layout.
+
+// schemaOf describes the layout of t, the aliased types are expanded
+func (table *typeTable) schemaOf(t reflect.Type) string {
+	if leaf := table.leafName(t); leaf != "" {
+		return leaf
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return "interface " + table.aliases[t]
+	case reflect.Struct:
+		fields := make([]string, t.NumField())
+		for i := range fields {
+			fields[i] = table.schemaOf(t.Field(i).Type)
+		}
+		return "{" + strings.Join(fields, ";") + "}"
+	case reflect.Slice:
+		return "[]" + table.schemaOf(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), table.schemaOf(t.Elem()))
+	case reflect.Ptr:
+		return "*" + table.schemaOf(t.Elem())
+	default:
+		return t.Kind().String()
+	}
+}
+
+// schemaHash returns the hash of the layout t, the layout of the type aliased
+// as name
+func (table *typeTable) schemaHash(name string, t reflect.Type) [4]byte {
+	var hash [4]byte
+	sum := sha256.Sum256([]byte(name + ":" + table.schemaOf(t)))
+	copy(hash[:], sum[:])
+	return hash
+}
+
+func hashLiteral(hash [4]byte) string {
+	return fmt.Sprintf("{%d, %d, %d, %d}", hash[0], hash[1], hash[2], hash[3])
+}
+
+// generateSchemaFuncs writes the schema hashes of the types and of their
+// legacy layouts, and the decoders of the legacy layouts, which upgrade the
+// decoded values by the UpgradeX functions in legacy.go
+func generateSchemaFuncs(w io.Writer, list []codon.AliasAndValue, leafTypes map[string]string) {
+	table := newTypeTable(list, leafTypes)
+	hashes := make(map[[4]byte]string)
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	add("var schemaHashes = map[string][4]byte{")
+	for _, alias := range table.structs {
+		hash := table.schemaHash(alias, table.types[alias])
+		hashes[hash] = alias
+		add("%q: %s,", alias, hashLiteral(hash))
+	}
+	legacyHashes := make([][4]byte, len(legacyTypes))
+	for i, legacy := range legacyTypes {
+		hash := table.schemaHash(legacy.Current, reflect.TypeOf(legacy.Value))
+		legacyHashes[i] = hash
+		if other, ok := hashes[hash]; ok {
+			panic(fmt.Sprintf("%s has the same schema hash as %s", legacy.Alias, other))
+		}
+		hashes[hash] = legacy.Alias
+		add("%q: %s,", legacy.Alias, hashLiteral(hash))
+	}
+	add("}")
+	add("")
+
+	add("var legacySchemas = map[[4]byte]legacySchema{")
+	for i, legacy := range legacyTypes {
+		add("%s: {%q, decodeLegacy%s},", hashLiteral(legacyHashes[i]), legacy.Current, legacy.Alias)
+	}
+	add("}")
+	add("")
+
+	for _, legacy := range legacyTypes {
+		add("func decodeLegacy%s(bz []byte) (interface{}, int, error) {", legacy.Alias)
+		add("v, n, err := Decode%s(bz)", legacy.Alias)
+		add("if err != nil {return nil, n, err}")
+		add("return Upgrade%s(v), n, nil", legacy.Alias)
+		add("}")
+		add("")
+	}
+	writeGeneratedLines(w, lines)
+}
+
+var funcName = regexp.MustCompile(`^func (\w+)\(`)
+
+// generateLegacyFuncs writes the functions generated by codon for the legacy
+// types, which are not added to EncodeAny, DecodeAny, etc.
+func generateLegacyFuncs(w io.Writer, list []codon.AliasAndValue, leafTypes map[string]string,
+	ignoreImpl map[string]string, extraImports []string) {
+
+	if len(legacyTypes) == 0 {
+		return
+	}
+	wanted := make(map[string]bool)
+	for _, legacy := range legacyTypes {
+		list = append(list, codon.AliasAndValue{Alias: legacy.Alias, Value: legacy.Value})
+		for _, prefix := range []string{"Encode", "Decode", "decode", "Rand"} {
+			wanted[prefix+legacy.Alias] = true
+		}
+	}
+	var buf strings.Builder
+	codon.GenerateCodecFile(&buf, leafTypes, ignoreImpl, list, extraLogics, extraImports)
+	for _, f := range splitFuncs(hardenDecoders(buf.String())) {
+		m := funcName.FindStringSubmatch(f)
+		if m == nil || !wanted[m[1]] {
+			continue
+		}
+		// drop the comments of the next function after the closing line
+		end := strings.LastIndex(f, "\n}") + 1
+		if i := strings.IndexByte(f[end:], '\n'); i >= 0 {
+			f = f[:end+i]
+		}
+		if _, err := io.WriteString(w, f+"\n\n"); err != nil {
+			panic(err)
+		}
+	}
+}
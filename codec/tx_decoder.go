@@ -0,0 +1,45 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// NewTxDecoder returns a TxDecoder of the length-prefixed StdTx encoded by
+// amino, which decodes it with the generated decoder instead of reflection.
+// The txs are accepted only if they are written back to the same bytes, the
+// others, e.g. the non-canonical or malformed ones and the txs of unknown
+// types, are decoded by fallback, which should be the amino decoder, so the
+// results and the errors are the same as amino's.
+func NewTxDecoder(fallback sdk.TxDecoder) sdk.TxDecoder {
+	return func(txBytes []byte) (sdk.Tx, sdk.Error) {
+		tx, ok := decodeAminoTx(txBytes)
+		if !ok {
+			return fallback(txBytes)
+		}
+		return tx, nil
+	}
+}
+
+func decodeAminoTx(txBytes []byte) (StdTx, bool) {
+	length, n := binary.Uvarint(txBytes)
+	if n <= 0 || length != uint64(len(txBytes)-n) {
+		return StdTx{}, false
+	}
+	bz := txBytes[n:]
+	if !bytes.HasPrefix(bz, aminoPrefixStdTx[:]) {
+		return StdTx{}, false
+	}
+	tx, err := decodeAminoStdTx(bz[len(aminoPrefixStdTx):], 0)
+	if err != nil {
+		return StdTx{}, false
+	}
+	w := bytes.NewBuffer(make([]byte, 0, len(bz)))
+	w.Write(aminoPrefixStdTx[:])
+	if err := encodeAminoStdTx(w, tx); err != nil || !bytes.Equal(w.Bytes(), bz) {
+		return StdTx{}, false
+	}
+	return tx, true
+}
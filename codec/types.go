@@ -86,9 +86,11 @@ type (
 	ModuleAccount                  = supply.ModuleAccount
 
 	AccountX                 = authx.AccountX
+	MsgSetReferee            = authx.MsgSetReferee
 	MsgMultiSendX            = bankx.MsgMultiSend
 	MsgSendX                 = bankx.MsgSend
 	MsgSetMemoRequired       = bankx.MsgSetMemoRequired
+	MsgSupervisedSend        = bankx.MsgSupervisedSend
 	BaseToken                = asset.BaseToken
 	MsgAddTokenWhitelist     = asset.MsgAddTokenWhitelist
 	MsgBurnToken             = asset.MsgBurnToken
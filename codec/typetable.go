@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/coinexchain/codon"
+)
+
+// typeTable describes the types given to codon, for the generators of the
+// functions which codon does not support
+type typeTable struct {
+	aliases   map[reflect.Type]string
+	leafNames map[string]string
+	ifcs      []string
+	structs   []string
+	types     map[string]reflect.Type
+}
+
+func newTypeTable(list []codon.AliasAndValue, leafTypes map[string]string) *typeTable {
+	table := &typeTable{
+		aliases:   make(map[reflect.Type]string),
+		leafNames: make(map[string]string),
+		types:     make(map[string]reflect.Type),
+	}
+	for path, name := range leafTypes {
+		// sdk.Int is handled by EncodeInt, DeepCopyInt, etc.
+		table.leafNames[path] = name[strings.LastIndex(name, ".")+1:]
+	}
+	for _, entry := range list {
+		t := reflect.TypeOf(entry.Value)
+		if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+			t = t.Elem()
+			table.ifcs = append(table.ifcs, entry.Alias)
+		} else {
+			table.structs = append(table.structs, entry.Alias)
+		}
+		table.aliases[t] = entry.Alias
+		table.types[entry.Alias] = t
+	}
+	sort.Strings(table.structs)
+	return table
+}
+
+func (table *typeTable) leafName(t reflect.Type) string {
+	return table.leafNames[t.PkgPath()+"."+t.Name()]
+}
+
+// implType is a type implementing an interface, the pointer of the aliased
+// type if ptr is true
+type implType struct {
+	alias string
+	ptr   bool
+}
+
+func (impl implType) typeName() string {
+	if impl.ptr {
+		return "*" + impl.alias
+	}
+	return impl.alias
+}
+
+// implementations returns the types implementing ifcType, all the types and
+// their pointers if ifcType is nil
+func (table *typeTable) implementations(ifcType reflect.Type) []implType {
+	impls := make([]implType, 0, 2*len(table.structs))
+	for _, alias := range table.structs {
+		t := table.types[alias]
+		if ifcType == nil || t.Implements(ifcType) {
+			impls = append(impls, implType{alias, false})
+		}
+		if ifcType == nil || reflect.PtrTo(t).Implements(ifcType) {
+			impls = append(impls, implType{alias, true})
+		}
+	}
+	return impls
+}
+
+func writeGeneratedLines(w io.Writer, lines []string) {
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// The encoding of EncodeAny has no version, the bytes written before a type
+// changes can not be decoded after the change. The data kept for long, e.g.
+// in archives, should be written by EncodeVersionedAny, whose output starts
+// with WireFormatVersion and the schema hash of the type:
+//
+//   version (1 byte) | schema hash (4 bytes) | magic bytes (4 bytes) | value
+//
+// DecodeVersionedAny decodes the current layout of a type as DecodeAny, and
+// its legacy layouts by the decoders generated for legacyTypes.
+
+const WireFormatVersion = 1
+
+const versionedHeaderLength = 1 + 4
+
+var (
+	ErrUnknownWireFormat = errors.New("codon: unknown wire format version")
+	ErrUnknownSchema     = errors.New("codon: unknown schema hash")
+)
+
+// legacySchema decodes a legacy layout of the type name into its current type
+type legacySchema struct {
+	name   string
+	decode func(bz []byte) (interface{}, int, error)
+}
+
+// the names of the current types, by their magic bytes
+var magicBytesToName = make(map[[4]byte]string)
+
+func init() {
+	for name, hash := range schemaHashes {
+		if _, ok := legacySchemas[hash]; ok {
+			continue
+		}
+		var magic [4]byte
+		copy(magic[:], getMagicBytes(name))
+		magicBytesToName[magic] = name
+	}
+}
+
+// GetSchemaHash returns the schema hash of the type or the legacy type
+// aliased as name
+func GetSchemaHash(name string) ([4]byte, bool) {
+	hash, ok := schemaHashes[name]
+	return hash, ok
+}
+
+func EncodeVersionedAny(w io.Writer, x interface{}) error {
+	var buf bytes.Buffer
+	err := EncodeAny(&buf, x)
+	if err != nil {
+		return err
+	}
+	var magic [4]byte
+	copy(magic[:], buf.Bytes())
+	name, ok := magicBytesToName[magic]
+	if !ok {
+		return ErrUnknownMagicBytes
+	}
+	hash := schemaHashes[name]
+	header := append([]byte{WireFormatVersion}, hash[:]...)
+	if _, err = w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func DecodeVersionedAny(bz []byte) (interface{}, int, error) {
+	if len(bz) < versionedHeaderLength+4 {
+		return nil, 0, ErrNotEnoughBytes
+	}
+	if bz[0] != WireFormatVersion {
+		return nil, 0, ErrUnknownWireFormat
+	}
+	var hash, magic [4]byte
+	copy(hash[:], bz[1:versionedHeaderLength])
+	copy(magic[:], bz[versionedHeaderLength:])
+
+	if name, ok := magicBytesToName[magic]; ok && schemaHashes[name] == hash {
+		v, n, err := DecodeAny(bz[versionedHeaderLength:])
+		return v, versionedHeaderLength + n, err
+	}
+	legacy, ok := legacySchemas[hash]
+	if !ok || !bytes.Equal(getMagicBytes(legacy.name), magic[:]) {
+		return nil, versionedHeaderLength, ErrUnknownSchema
+	}
+	v, n, err := legacy.decode(bz[versionedHeaderLength+4:])
+	return v, versionedHeaderLength + 4 + n, err
+}
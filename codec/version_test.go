@@ -0,0 +1,88 @@
+package codec_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/coinexchain/dex/codec"
+)
+
+func TestVersionedAny(t *testing.T) {
+	r := codec.NewRandSrc(0)
+	for i := 0; i < 10000; i++ {
+		v := codec.RandAny(r)
+		var buf bytes.Buffer
+		require.Nil(t, codec.EncodeVersionedAny(&buf, v))
+		bz := buf.Bytes()
+		require.Equal(t, byte(codec.WireFormatVersion), bz[0])
+		require.Equal(t, encodeAny(t, v), bz[5:])
+
+		decoded, n, err := codec.DecodeVersionedAny(bz)
+		require.Nil(t, err)
+		require.Equal(t, len(bz), n)
+		require.True(t, codec.EqualAny(v, decoded))
+	}
+}
+
+// encodeAccountXV1 writes an AccountX of DEX1 as EncodeVersionedAny did
+func encodeAccountXV1(t *testing.T, v codec.AccountXV1) []byte {
+	hash, ok := codec.GetSchemaHash("AccountXV1")
+	require.True(t, ok)
+	bz := append([]byte{codec.WireFormatVersion}, hash[:]...)
+	bz = append(bz, magicBytesOf(t, codec.AccountX{})...)
+	var buf bytes.Buffer
+	require.Nil(t, codec.EncodeAccountXV1(&buf, v))
+	return append(bz, buf.Bytes()...)
+}
+
+func TestDecodeLegacyAccountX(t *testing.T) {
+	current, _ := codec.GetSchemaHash("AccountX")
+	legacy, _ := codec.GetSchemaHash("AccountXV1")
+	require.NotEqual(t, current, legacy)
+
+	r := codec.NewRandSrc(0)
+	for i := 0; i < 1000; i++ {
+		old := codec.RandAccountXV1(r)
+		bz := encodeAccountXV1(t, old)
+
+		// the old layout can not be decoded as the current one
+		_, _, err := codec.DecodeAny(bz[5:])
+		require.NotNil(t, err)
+
+		decoded, n, err := codec.DecodeVersionedAny(bz)
+		require.Nil(t, err)
+		require.Equal(t, len(bz), n)
+		require.True(t, codec.EqualAccountX(codec.UpgradeAccountXV1(old), decoded.(codec.AccountX)))
+	}
+}
+
+func TestDecodeVersionedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, codec.EncodeVersionedAny(&buf, codec.RandCoin(codec.NewRandSrc(0))))
+	valid := buf.Bytes()
+	change := func(i int, b byte) []byte {
+		bz := append([]byte(nil), valid...)
+		bz[i] = b
+		return bz
+	}
+	legacy, _ := codec.GetSchemaHash("AccountXV1")
+	wrongMagic := append(append([]byte{codec.WireFormatVersion}, legacy[:]...), valid[5:]...)
+
+	testCases := []struct {
+		name  string
+		input []byte
+		err   error
+	}{
+		{"short", valid[:8], codec.ErrNotEnoughBytes},
+		{"unknown version", change(0, codec.WireFormatVersion+1), codec.ErrUnknownWireFormat},
+		{"unknown schema", change(1, valid[1]+1), codec.ErrUnknownSchema},
+		{"legacy schema of another type", wrongMagic, codec.ErrUnknownSchema},
+		{"truncated value", valid[:len(valid)-1], codec.ErrNotEnoughBytes},
+	}
+	for _, tc := range testCases {
+		_, _, err := codec.DecodeVersionedAny(tc.input)
+		require.Equal(t, tc.err, err, tc.name)
+	}
+}